@@ -0,0 +1,174 @@
+package alarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"logvault/store"
+)
+
+// SchemaVersion is the version of Document written by this release. Values
+// stored by earlier releases have no version and are treated as version 1.
+const SchemaVersion = 2
+
+// Severities are the syslog severity keywords, most severe first.
+var Severities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// Document is the stored form of an alarm. Fields holds parsed key/value
+// data, such as the columns of an INSIGHTS message; Raw holds the syslog
+// message as received, if there was one. Fingerprint, when set, identifies
+// recurrences of the same alarm. Assignee is the user who claimed the
+// alarm, if any. SilencedBy is the ID of the silence, and Maintenance the
+// name of the maintenance window, that suppressed the alarm's notification.
+// Incident is the ID of the incident the alarm was grouped into, if any, and
+// Labels are free-form labels operators put on it. CreatedBy is set on alarms
+// an operator raised by hand and names that operator.
+// Comments are investigation notes and Escalations the escalation history,
+// both oldest first.
+type Document struct {
+	Version     int               `json:"v"`
+	ID          string            `json:"id"`
+	Tag         string            `json:"tag"`
+	ReceivedAt  time.Time         `json:"received_at"`
+	Severity    string            `json:"severity,omitempty"`
+	Source      Source            `json:"source"`
+	CreatedBy   string            `json:"created_by,omitempty"`
+	Fields      map[string]string `json:"fields,omitempty"`
+	Raw         string            `json:"raw,omitempty"`
+	Fingerprint string            `json:"fingerprint,omitempty"`
+	State       State             `json:"state"`
+	Assignee    string            `json:"assignee,omitempty"`
+	SilencedBy  string            `json:"silenced_by,omitempty"`
+	Maintenance string            `json:"maintenance,omitempty"`
+	Incident    string            `json:"incident,omitempty"`
+	Labels      []string          `json:"labels,omitempty"`
+	Comments    []Comment         `json:"comments,omitempty"`
+	Escalations []Escalation      `json:"escalations,omitempty"`
+}
+
+// Source identifies the syslog sender an alarm came from.
+type Source struct {
+	IP       string `json:"ip,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// State is the lifecycle state of an alarm. History is its audit trail,
+// oldest first.
+type State struct {
+	Status  string       `json:"status"`
+	Ack     *Ack         `json:"ack,omitempty"`
+	History []Transition `json:"history,omitempty"`
+}
+
+// New returns an open alarm document at the current schema version.
+func New(id, tag string, receivedAt time.Time) Document {
+	return Document{
+		Version:    SchemaVersion,
+		ID:         id,
+		Tag:        tag,
+		ReceivedAt: receivedAt,
+		State:      State{Status: StatusOpen},
+	}
+}
+
+// Suppressed reports whether the alarm was raised during a silence or
+// maintenance window, so it was not sent to the external API.
+func (d Document) Suppressed() bool {
+	return d.SilencedBy != "" || d.Maintenance != ""
+}
+
+// Record encodes the document for storage.
+func (d Document) Record() (store.Record, error) {
+	value, err := json.Marshal(d)
+	if err != nil {
+		return store.Record{}, err
+	}
+	return store.Record{ID: d.ID, Tag: d.Tag, ReceivedAt: d.ReceivedAt, Value: string(value)}, nil
+}
+
+// Message returns a one-line description of the alarm: the "message" field
+// if there is one, otherwise the raw syslog message.
+func (d Document) Message() string {
+	if msg := d.Fields["message"]; msg != "" {
+		return msg
+	}
+	return d.Raw
+}
+
+// Decode returns the document stored in rec, upgrading values written by
+// earlier releases. The second result reports whether an upgrade was needed.
+func Decode(rec store.Record) (Document, bool) {
+	var probe struct {
+		Version int `json:"v"`
+	}
+	if err := json.Unmarshal([]byte(rec.Value), &probe); err == nil && probe.Version >= SchemaVersion {
+		var doc Document
+		if err := json.Unmarshal([]byte(rec.Value), &doc); err == nil {
+			if doc.ID == "" {
+				doc.ID = rec.ID
+			}
+			return doc, false
+		}
+	}
+	return upgradeV1(rec), true
+}
+
+// legacyKeys are the top-level keys of version 1 values that map onto
+// Document fields rather than into Fields.
+var legacyKeys = map[string]bool{"tag": true, "received_at": true, "severity": true}
+
+// upgradeV1 converts an unversioned value. Those were either plain strings,
+// {"tag","message",...} objects from the generic handler, or flat objects
+// holding the parsed columns of an INSIGHTS message.
+func upgradeV1(rec store.Record) Document {
+	doc := New(rec.ID, rec.Tag, rec.ReceivedAt)
+
+	var legacy map[string]interface{}
+	if err := json.Unmarshal([]byte(rec.Value), &legacy); err != nil {
+		doc.Raw = rec.Value
+		return doc
+	}
+
+	if tag, ok := legacy["tag"].(string); ok && tag != "" {
+		doc.Tag = tag
+	}
+	if s, ok := legacy["received_at"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil && rec.ReceivedAt.IsZero() {
+			doc.ReceivedAt = t
+		}
+	}
+	if severity, ok := legacy["severity"].(string); ok {
+		doc.Severity = severity
+	}
+
+	fields := make(map[string]string)
+	for k, v := range legacy {
+		if !legacyKeys[k] {
+			fields[k] = fieldString(v)
+		}
+	}
+	if msg, ok := fields["message"]; ok && len(fields) == 1 {
+		doc.Raw = msg
+	} else if len(fields) > 0 {
+		doc.Fields = fields
+	}
+	return doc
+}
+
+func fieldString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64, bool:
+		return fmt.Sprint(t)
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprint(t)
+		}
+		return string(b)
+	}
+}
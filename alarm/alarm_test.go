@@ -0,0 +1,107 @@
+package alarm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"logvault/store"
+)
+
+func TestDecodeUpgradesLegacyValues(t *testing.T) {
+	receivedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name  string
+		value string
+		check func(t *testing.T, doc Document)
+	}{
+		{"plain string", `disk full`, func(t *testing.T, doc Document) {
+			if doc.Raw != "disk full" || doc.Fields != nil {
+				t.Fatalf("expected raw text only, got %+v", doc)
+			}
+		}},
+		{"generic message", `{"tag":"ALARM","message":"disk full","severity":"err"}`, func(t *testing.T, doc Document) {
+			if doc.Tag != "ALARM" || doc.Raw != "disk full" || doc.Severity != "err" || doc.Fields != nil {
+				t.Fatalf("expected message to become raw, got %+v", doc)
+			}
+		}},
+		{"insights columns", `{"tag":"INSIGHTS","RuleName":"R1","IP":"192.0.2.10","received_at":"2026-01-02T03:04:05Z"}`, func(t *testing.T, doc Document) {
+			if doc.Fields["RuleName"] != "R1" || doc.Fields["IP"] != "192.0.2.10" || doc.Raw != "" {
+				t.Fatalf("expected columns to become fields, got %+v", doc)
+			}
+			if _, ok := doc.Fields["received_at"]; ok {
+				t.Fatal("expected received_at not to be copied into fields")
+			}
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, upgraded := Decode(store.Record{ID: "a1", Tag: "ALARM", ReceivedAt: receivedAt, Value: tc.value})
+			if !upgraded || doc.Version != SchemaVersion || doc.ID != "a1" || doc.State.Status != StatusOpen {
+				t.Fatalf("expected upgraded open document, got %+v (upgraded=%v)", doc, upgraded)
+			}
+			if !doc.ReceivedAt.Equal(receivedAt) {
+				t.Fatalf("expected received time to be kept, got %v", doc.ReceivedAt)
+			}
+			tc.check(t, doc)
+		})
+	}
+}
+
+func TestDecodeKeepsCurrentDocuments(t *testing.T) {
+	doc := New("a1", "ALARM", time.Now().UTC())
+	doc.Raw = "disk full"
+	doc.Source = Source{IP: "10.0.0.5", Hostname: "fw01"}
+	rec, err := doc.Record()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, upgraded := Decode(rec)
+	if upgraded || got.Source != doc.Source || got.Raw != doc.Raw {
+		t.Fatalf("expected document to round-trip unchanged, got %+v (upgraded=%v)", got, upgraded)
+	}
+}
+
+func TestMigrateRewritesLegacyAlarms(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	current, _ := New("new", "ALARM", time.Now()).Record()
+	s.Put(ctx, current)
+	s.Put(ctx, store.Record{ID: "old", Tag: "ALARM", ReceivedAt: time.Now(), Value: `{"tag":"ALARM","message":"x"}`})
+
+	total, upgraded, err := Migrate(ctx, s, true)
+	if err != nil || total != 2 || upgraded != 1 {
+		t.Fatalf("unexpected dry-run result %d/%d, %v", upgraded, total, err)
+	}
+	if rec, _ := s.Get(ctx, "old"); rec.Value != `{"tag":"ALARM","message":"x"}` {
+		t.Fatal("expected dry run to leave the value unchanged")
+	}
+
+	if _, upgraded, err = Migrate(ctx, s, false); err != nil || upgraded != 1 {
+		t.Fatalf("unexpected migration result %d, %v", upgraded, err)
+	}
+	rec, _ := s.Get(ctx, "old")
+	if _, needed := Decode(rec); needed {
+		t.Fatalf("expected stored value to be current after migration, got %s", rec.Value)
+	}
+}
+
+func TestUpgradingStoreUpgradesQueryResults(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	s.Put(ctx, store.Record{ID: "old", Tag: "ALARM", ReceivedAt: time.Now(), Value: `legacy text`})
+
+	rec, err := UpgradeOnRead(s).Get(ctx, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc, needed := Decode(rec); needed || doc.Raw != "legacy text" {
+		t.Fatalf("expected upgraded record, got %s", rec.Value)
+	}
+	if raw, _ := s.Get(ctx, "old"); raw.Value != "legacy text" {
+		t.Fatal("expected the stored value to be left alone")
+	}
+}
@@ -0,0 +1,50 @@
+package alarm
+
+import (
+	"context"
+	"errors"
+
+	"logvault/store"
+)
+
+var errUnchanged = errors.New("alarm unchanged")
+
+// ErrClaimed is returned by AssignOwn when the alarm is assigned to another
+// user.
+var ErrClaimed = errors.New("alarm is assigned to another user")
+
+// Assign sets the assignee of the stored alarm id, or clears it when user is
+// empty, and records the change in the event history. It reports whether the
+// assignee changed; assigning the current assignee again writes nothing.
+func Assign(ctx context.Context, alarms store.AlarmStore, events store.EventLog, id, user, by string) (Document, bool, error) {
+	return assign(ctx, alarms, events, id, user, by, false)
+}
+
+// AssignOwn is Assign for users who may only claim an alarm for themselves,
+// with user set to by, or release it, with user empty. It returns ErrClaimed
+// if the alarm is assigned to someone else. The check is part of the update,
+// so a claim made concurrently is never overwritten.
+func AssignOwn(ctx context.Context, alarms store.AlarmStore, events store.EventLog, id, user, by string) (Document, bool, error) {
+	return assign(ctx, alarms, events, id, user, by, true)
+}
+
+func assign(ctx context.Context, alarms store.AlarmStore, events store.EventLog, id, user, by string, own bool) (Document, bool, error) {
+	message := "Unassigned"
+	if user != "" {
+		message = "Assigned to " + user
+	}
+	doc, err := update(ctx, alarms, events, id, store.EventAssign, by, message, func(doc *Document) error {
+		if doc.Assignee == user {
+			return errUnchanged
+		}
+		if own && doc.Assignee != "" && doc.Assignee != by {
+			return ErrClaimed
+		}
+		doc.Assignee = user
+		return nil
+	})
+	if errors.Is(err, errUnchanged) {
+		return doc, false, nil
+	}
+	return doc, err == nil, err
+}
@@ -0,0 +1,26 @@
+package alarm
+
+import (
+	"context"
+	"time"
+
+	"logvault/store"
+)
+
+// Comment is an investigation note on an alarm. Text is kept as plain text.
+type Comment struct {
+	By   string    `json:"by"`
+	At   time.Time `json:"at"`
+	Text string    `json:"text"`
+}
+
+// AddComment appends a comment to the stored alarm id and records it in the
+// event history. It returns store.ErrNotFound if the alarm does not exist.
+func AddComment(ctx context.Context, alarms store.AlarmStore, events store.EventLog, id, by, text string) (Comment, error) {
+	c := Comment{By: by, At: time.Now(), Text: text}
+	_, err := update(ctx, alarms, events, id, store.EventComment, by, text, func(doc *Document) error {
+		doc.Comments = append(doc.Comments, c)
+		return nil
+	})
+	return c, err
+}
@@ -0,0 +1,75 @@
+package alarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"logvault/store"
+)
+
+// Escalation is one step of an alarm's escalation history: a level of an
+// escalation policy that was notified, or, with Cancelled set, the end of
+// escalation when the alarm stopped being open.
+type Escalation struct {
+	Policy    string    `json:"policy,omitempty"`
+	Level     int       `json:"level,omitempty"`
+	Target    string    `json:"target,omitempty"`
+	At        time.Time `json:"at"`
+	Cancelled bool      `json:"cancelled,omitempty"`
+	By        string    `json:"by,omitempty"`
+}
+
+// OpenedAt returns when the alarm last became open: its latest transition
+// to open, or when it was received.
+func (d Document) OpenedAt() time.Time {
+	for i := len(d.State.History) - 1; i >= 0; i-- {
+		if t := d.State.History[i]; t.To == StatusOpen {
+			return t.At
+		}
+	}
+	return d.ReceivedAt
+}
+
+// EscalationLevel returns the highest escalation level notified since the
+// alarm last became open, or 0 if it has not been escalated since.
+func (d Document) EscalationLevel() int {
+	opened := d.OpenedAt()
+	level := 0
+	for _, e := range d.Escalations {
+		if !e.At.Before(opened) && !e.Cancelled && e.Level > level {
+			level = e.Level
+		}
+	}
+	return level
+}
+
+// cancelEscalation records that escalation stopped because the alarm left
+// the open status, if it had been escalated.
+func (d *Document) cancelEscalation(by string, at time.Time) {
+	if d.EscalationLevel() > 0 {
+		d.Escalations = append(d.Escalations, Escalation{At: at, Cancelled: true, By: by})
+	}
+}
+
+// Escalate records that level e.Level of a policy was notified for the
+// stored alarm id, and adds it to the event history. It reports false,
+// writing nothing, unless the alarm is still open and e is its next level.
+// The check and the write are one compare-and-swap, so an alarm that was
+// acknowledged or escalated by another sweep after it was read is checked
+// again and not escalated.
+func Escalate(ctx context.Context, alarms store.AlarmStore, events store.EventLog, id string, e Escalation) (Document, bool, error) {
+	message := fmt.Sprintf("Escalated to %s (%s level %d)", e.Target, e.Policy, e.Level)
+	doc, err := update(ctx, alarms, events, id, store.EventEscalate, "escalation", message, func(doc *Document) error {
+		if doc.Status() != StatusOpen || doc.EscalationLevel() != e.Level-1 {
+			return errUnchanged
+		}
+		doc.Escalations = append(doc.Escalations, e)
+		return nil
+	})
+	if errors.Is(err, errUnchanged) {
+		return doc, false, nil
+	}
+	return doc, err == nil, err
+}
@@ -0,0 +1,132 @@
+package alarm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrInvalidFilter is returned for filter expressions that cannot be parsed.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Filter selects alarms with an expression of space-separated terms, all of
+// which must match. A term is key=value or key!=value; values with spaces
+// are double-quoted. Keys are tag, status, severity, sender (an IP, CIDR
+// range or hostname), assignee, incident and label; any other key is looked
+// up in the alarm's fields. Values compare case-insensitively.
+type Filter struct {
+	terms []term
+}
+
+type term struct {
+	key    string
+	value  string
+	negate bool
+}
+
+// ParseFilter parses a filter expression. An empty expression is invalid,
+// so a typo cannot select every alarm.
+func ParseFilter(expr string) (Filter, error) {
+	tokens, err := splitTerms(expr)
+	if err != nil {
+		return Filter{}, err
+	}
+	if len(tokens) == 0 {
+		return Filter{}, fmt.Errorf("%w: no terms", ErrInvalidFilter)
+	}
+
+	var f Filter
+	for _, tok := range tokens {
+		key, value, ok := strings.Cut(tok, "=")
+		if !ok || key == "" || key == "!" {
+			return Filter{}, fmt.Errorf("%w: %q is not key=value", ErrInvalidFilter, tok)
+		}
+		t := term{key: key, value: strings.Trim(value, `"`)}
+		if strings.HasSuffix(key, "!") {
+			t.key, t.negate = strings.TrimSuffix(key, "!"), true
+		}
+		if t.key == "sender" && strings.Contains(t.value, "/") {
+			if _, _, err := net.ParseCIDR(t.value); err != nil {
+				return Filter{}, fmt.Errorf("%w: %q is not a valid CIDR range", ErrInvalidFilter, t.value)
+			}
+		}
+		f.terms = append(f.terms, t)
+	}
+	return f, nil
+}
+
+// splitTerms splits expr at spaces outside double quotes.
+func splitTerms(expr string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	quoted := false
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			cur.WriteRune(r)
+		case (r == ' ' || r == '\t') && !quoted:
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("%w: unterminated quote", ErrInvalidFilter)
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens, nil
+}
+
+// Tag returns the tag the filter requires, if any, so callers can narrow
+// a store query. It is the only term backed by an index; filters without it
+// have to be matched against every stored alarm.
+func (f Filter) Tag() string {
+	for _, t := range f.terms {
+		if t.key == "tag" && !t.negate {
+			return t.value
+		}
+	}
+	return ""
+}
+
+// Matches reports whether doc satisfies every term of the filter.
+func (f Filter) Matches(doc Document) bool {
+	for _, t := range f.terms {
+		if t.matches(doc) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (t term) matches(doc Document) bool {
+	switch t.key {
+	case "tag":
+		return strings.EqualFold(doc.Tag, t.value)
+	case "status":
+		return strings.EqualFold(doc.Status(), t.value)
+	case "severity":
+		return strings.EqualFold(doc.Severity, t.value)
+	case "assignee":
+		return strings.EqualFold(doc.Assignee, t.value)
+	case "incident":
+		return strings.EqualFold(doc.Incident, t.value)
+	case "label":
+		return doc.HasLabel(t.value)
+	case "sender":
+		if _, network, err := net.ParseCIDR(t.value); err == nil {
+			ip := net.ParseIP(doc.Source.IP)
+			return ip != nil && network.Contains(ip)
+		}
+		return doc.Source.IP == t.value || strings.EqualFold(doc.Source.Hostname, t.value)
+	default:
+		return strings.EqualFold(doc.Fields[t.key], t.value)
+	}
+}
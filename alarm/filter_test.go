@@ -0,0 +1,46 @@
+package alarm
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFilterMatchesTerms(t *testing.T) {
+	doc := New("a1", "INSIGHTS", time.Now())
+	doc.Severity = "crit"
+	doc.Source = Source{IP: "10.0.0.5", Hostname: "edge-fw"}
+	doc.Fields = map[string]string{"RuleName": "Blocked VirusX"}
+	doc.Labels = []string{"false-positive"}
+	doc.Assignee = "bob"
+	doc.Incident = "inc-1a2b"
+
+	for expr, want := range map[string]bool{
+		`tag=insights status=open`:                true,
+		`sender=10.0.0.0/24 severity=crit`:        true,
+		`sender=edge-fw`:                          true,
+		`RuleName="blocked virusx"`:               true,
+		`label=false-positive`:                    true,
+		`label!=false-positive`:                   false,
+		`tag=INSIGHTS status!=open`:               false,
+		`sender=192.168.0.0/16`:                   false,
+		`tag=INSIGHTS RuleName="Blocked VirusY"`:  false,
+		`assignee=alice`:                          false,
+		`assignee!=alice incident!=x status=open`: true,
+		`assignee=BOB incident=INC-1A2B`:          true,
+	} {
+		f, err := ParseFilter(expr)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", expr, err)
+		}
+		if got := f.Matches(doc); got != want {
+			t.Fatalf("%s: expected %v, got %v", expr, want, got)
+		}
+	}
+
+	for _, expr := range []string{"", "   ", "tag", `RuleName="open`, "sender=10.0.0.0/99"} {
+		if _, err := ParseFilter(expr); !errors.Is(err, ErrInvalidFilter) {
+			t.Fatalf("%q: expected ErrInvalidFilter, got %v", expr, err)
+		}
+	}
+}
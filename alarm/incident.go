@@ -0,0 +1,18 @@
+package alarm
+
+import (
+	"context"
+
+	"logvault/store"
+)
+
+// SetIncident records on the stored alarm id that it was grouped into
+// incident. Grouping is part of receiving the alarm, so no event is added to
+// the event history.
+func SetIncident(ctx context.Context, alarms store.AlarmStore, id, incident string) (Document, error) {
+	doc, _, err := swap(ctx, alarms, id, func(doc *Document) error {
+		doc.Incident = incident
+		return nil
+	})
+	return doc, err
+}
@@ -0,0 +1,69 @@
+package alarm
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"logvault/store"
+)
+
+// HasLabel reports whether the alarm carries label, ignoring case.
+func (d Document) HasLabel(label string) bool {
+	for _, l := range d.Labels {
+		if strings.EqualFold(l, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// Label adds and removes labels on the stored alarm id and records the change
+// in the event history. It reports whether the labels changed; nothing is
+// written if they did not.
+func Label(ctx context.Context, alarms store.AlarmStore, events store.EventLog, id string, add, remove []string, by string) (Document, bool, error) {
+	var changes []string
+	for _, l := range add {
+		changes = append(changes, "+"+strings.TrimSpace(l))
+	}
+	for _, l := range remove {
+		changes = append(changes, "-"+strings.TrimSpace(l))
+	}
+	message := "Labels " + strings.Join(changes, " ")
+
+	doc, err := update(ctx, alarms, events, id, store.EventLabel, by, message, func(doc *Document) error {
+		changed := false
+		var kept []string
+		for _, l := range doc.Labels {
+			if containsFold(remove, l) {
+				changed = true
+				continue
+			}
+			kept = append(kept, l)
+		}
+		doc.Labels = kept
+		for _, l := range add {
+			if l = strings.TrimSpace(l); l != "" && !doc.HasLabel(l) {
+				doc.Labels = append(doc.Labels, l)
+				changed = true
+			}
+		}
+		if !changed {
+			return errUnchanged
+		}
+		return nil
+	})
+	if errors.Is(err, errUnchanged) {
+		return doc, false, nil
+	}
+	return doc, err == nil, err
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
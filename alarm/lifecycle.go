@@ -0,0 +1,242 @@
+package alarm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"logvault/store"
+)
+
+// Alarm statuses. Alarms move open → acknowledged → resolved → closed; a
+// resolved or closed alarm is reopened when it recurs.
+const (
+	StatusOpen         = "open"
+	StatusAcknowledged = "acknowledged"
+	StatusResolved     = "resolved"
+	StatusClosed       = "closed"
+)
+
+// ErrInvalidTransition is returned when an action does not apply to the
+// alarm's current status.
+var ErrInvalidTransition = errors.New("invalid alarm status transition")
+
+// Action is a named status change. Its name is also the event history type.
+type Action struct {
+	Name string
+	To   string
+	From []string
+}
+
+// Actions of the alarm lifecycle.
+var (
+	ActionAck     = Action{Name: store.EventAck, To: StatusAcknowledged, From: []string{StatusOpen}}
+	ActionUnack   = Action{Name: store.EventUnack, To: StatusOpen, From: []string{StatusAcknowledged}}
+	ActionResolve = Action{Name: store.EventResolve, To: StatusResolved, From: []string{StatusOpen, StatusAcknowledged}}
+	ActionClose   = Action{Name: store.EventClose, To: StatusClosed, From: []string{StatusOpen, StatusAcknowledged, StatusResolved}}
+	ActionReopen  = Action{Name: store.EventReopen, To: StatusOpen, From: []string{StatusResolved, StatusClosed}}
+)
+
+var actions = map[string]Action{
+	ActionAck.Name:     ActionAck,
+	ActionUnack.Name:   ActionUnack,
+	ActionResolve.Name: ActionResolve,
+	ActionClose.Name:   ActionClose,
+	ActionReopen.Name:  ActionReopen,
+}
+
+// ActionByName returns the lifecycle action called name, e.g. "resolve".
+func ActionByName(name string) (Action, bool) {
+	a, ok := actions[strings.ToLower(name)]
+	return a, ok
+}
+
+// Allows reports whether the action applies to an alarm in status.
+func (a Action) Allows(status string) bool {
+	for _, from := range a.From {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Ack records who acknowledged an alarm, when, and why.
+type Ack struct {
+	By      string    `json:"by"`
+	At      time.Time `json:"at"`
+	Comment string    `json:"comment,omitempty"`
+}
+
+// Transition is one entry of an alarm's audit trail.
+type Transition struct {
+	Action  string    `json:"action"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	By      string    `json:"by"`
+	At      time.Time `json:"at"`
+	Comment string    `json:"comment,omitempty"`
+}
+
+// Status returns the lifecycle status, treating an unset status as open.
+func (d Document) Status() string {
+	return d.State.Current()
+}
+
+// Current returns the status, treating an unset status as open.
+func (s State) Current() string {
+	if s.Status == "" {
+		return StatusOpen
+	}
+	return s.Status
+}
+
+// Active reports whether the state is open or acknowledged.
+func (s State) Active() bool {
+	c := s.Current()
+	return c == StatusOpen || c == StatusAcknowledged
+}
+
+// Acknowledged reports whether the alarm is currently acknowledged.
+func (d Document) Acknowledged() bool {
+	return d.Status() == StatusAcknowledged
+}
+
+// Active reports whether the alarm still needs attention, i.e. it is open or
+// acknowledged.
+func (d Document) Active() bool {
+	return d.State.Active()
+}
+
+// Apply performs the action and appends it to the audit trail. It returns
+// ErrInvalidTransition if the action does not apply to the current status.
+// Leaving the open status cancels escalation.
+func (d *Document) Apply(a Action, by, comment string, at time.Time) error {
+	from := d.Status()
+	if err := d.State.Apply(a, by, comment, at); err != nil {
+		return err
+	}
+	if from == StatusOpen && a.To != StatusOpen {
+		d.cancelEscalation(by, at)
+	}
+	return nil
+}
+
+// Apply performs the action on the state and appends it to the audit trail.
+// It returns ErrInvalidTransition if the action does not apply to the
+// current status.
+func (s *State) Apply(a Action, by, comment string, at time.Time) error {
+	from := s.Current()
+	if !a.Allows(from) {
+		return fmt.Errorf("%w: cannot %s a %s alarm", ErrInvalidTransition, a.Name, from)
+	}
+
+	switch a.To {
+	case StatusAcknowledged:
+		s.Ack = &Ack{By: by, At: at, Comment: comment}
+	case StatusOpen:
+		s.Ack = nil
+	}
+	s.Status = a.To
+	s.History = append(s.History, Transition{
+		Action:  a.Name,
+		From:    from,
+		To:      a.To,
+		By:      by,
+		At:      at,
+		Comment: comment,
+	})
+	return nil
+}
+
+// Recur makes d, a new occurrence of prev, replace it: d takes prev's ID,
+// audit trail, assignee, labels, comments and escalation history and, if
+// prev was resolved or closed, reopens it. It reports whether prev was
+// reopened.
+func (d *Document) Recur(prev Document, by string) bool {
+	d.ID = prev.ID
+	d.State = prev.State
+	d.Assignee = prev.Assignee
+	d.Labels = prev.Labels
+	d.Comments = prev.Comments
+	d.Escalations = prev.Escalations
+	return d.Apply(ActionReopen, by, "Recurred", d.ReceivedAt) == nil
+}
+
+// Fingerprint identifies recurrences of the same alarm. Callers pass the
+// values that make two alarms "the same", such as the tag, sender and rule.
+func Fingerprint(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Change applies an action to the stored alarm id and records it in the
+// event history. It returns store.ErrNotFound or ErrInvalidTransition as
+// appropriate, and the updated document on success.
+func Change(ctx context.Context, alarms store.AlarmStore, events store.EventLog, id string, a Action, by, comment string) (Document, error) {
+	return update(ctx, alarms, events, id, a.Name, by, comment, func(doc *Document) error {
+		return doc.Apply(a, by, comment, time.Now())
+	})
+}
+
+// maxUpdateAttempts bounds how often swap retries when other writers keep
+// changing the alarm between its read and its write.
+const maxUpdateAttempts = 10
+
+// update reads the stored alarm id, lets fn modify it, writes it back and
+// appends an event of type eventType. Nothing is written if fn fails.
+func update(ctx context.Context, alarms store.AlarmStore, events store.EventLog, id, eventType, by, message string, fn func(*Document) error) (Document, error) {
+	doc, updated, err := swap(ctx, alarms, id, fn)
+	if err != nil {
+		return doc, err
+	}
+
+	err = events.Append(ctx, store.Event{
+		Type:    eventType,
+		AlarmID: id,
+		Tag:     doc.Tag,
+		Actor:   by,
+		Message: message,
+		Data:    updated.Value,
+	})
+	if err != nil {
+		log.Printf("Failed to record %s event for key %s: %v", eventType, store.Key(id), err)
+	}
+	return doc, nil
+}
+
+// swap reads the stored alarm id, lets fn modify it and writes it back,
+// returning the document and the record written. The write only succeeds if
+// the alarm is unchanged since it was read; otherwise fn runs again on the
+// current alarm, so concurrent changes are never lost and fn's checks always
+// see the alarm it modifies.
+func swap(ctx context.Context, alarms store.AlarmStore, id string, fn func(*Document) error) (Document, store.Record, error) {
+	for attempt := 1; ; attempt++ {
+		rec, err := alarms.Get(ctx, id)
+		if err != nil {
+			return Document{}, store.Record{}, err
+		}
+
+		doc, _ := Decode(rec)
+		if err := fn(&doc); err != nil {
+			return doc, store.Record{}, err
+		}
+
+		updated, err := doc.Record()
+		if err != nil {
+			return doc, store.Record{}, err
+		}
+		if updated.ReceivedAt.IsZero() {
+			updated.ReceivedAt = rec.ReceivedAt
+		}
+		err = alarms.Swap(ctx, rec, updated)
+		if err == nil || !errors.Is(err, store.ErrConflict) || attempt == maxUpdateAttempts {
+			return doc, updated, err
+		}
+	}
+}
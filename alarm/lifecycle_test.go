@@ -0,0 +1,242 @@
+package alarm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"logvault/store"
+)
+
+func TestApplyFollowsLifecycle(t *testing.T) {
+	now := time.Now()
+	doc := New("a1", "ALARM", now)
+
+	steps := []struct {
+		action Action
+		status string
+	}{
+		{ActionAck, StatusAcknowledged},
+		{ActionResolve, StatusResolved},
+		{ActionClose, StatusClosed},
+		{ActionReopen, StatusOpen},
+	}
+	for _, step := range steps {
+		if err := doc.Apply(step.action, "admin", "", now); err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.action.Name, err)
+		}
+		if doc.Status() != step.status {
+			t.Fatalf("%s: expected status %s, got %s", step.action.Name, step.status, doc.Status())
+		}
+	}
+
+	if len(doc.State.History) != len(steps) {
+		t.Fatalf("expected one history entry per transition, got %+v", doc.State.History)
+	}
+	if first := doc.State.History[0]; first.From != StatusOpen || first.To != StatusAcknowledged || first.By != "admin" {
+		t.Fatalf("unexpected first transition %+v", first)
+	}
+	if doc.State.Ack != nil {
+		t.Fatal("expected reopen to clear the acknowledgement")
+	}
+}
+
+func TestApplyRejectsInvalidTransitions(t *testing.T) {
+	doc := New("a1", "ALARM", time.Now())
+
+	if err := doc.Apply(ActionUnack, "admin", "", time.Now()); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected unack of an open alarm to fail, got %v", err)
+	}
+	if err := doc.Apply(ActionReopen, "admin", "", time.Now()); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected reopen of an open alarm to fail, got %v", err)
+	}
+	doc.Apply(ActionClose, "admin", "", time.Now())
+	if err := doc.Apply(ActionResolve, "admin", "", time.Now()); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected resolve of a closed alarm to fail, got %v", err)
+	}
+	if len(doc.State.History) != 1 {
+		t.Fatalf("expected only the close to be recorded, got %+v", doc.State.History)
+	}
+}
+
+func TestRecurReopensResolvedAlarm(t *testing.T) {
+	prev := New("a1", "ALARM", time.Now().Add(-time.Hour))
+	prev.Apply(ActionResolve, "admin", "fixed", time.Now())
+
+	doc := New("a2", "ALARM", time.Now())
+	if !doc.Recur(prev, "syslog") {
+		t.Fatal("expected resolved alarm to be reopened")
+	}
+	if doc.ID != "a1" || doc.Status() != StatusOpen || len(doc.State.History) != 2 {
+		t.Fatalf("expected a1 to be reopened with its history, got %+v", doc)
+	}
+}
+
+func TestChangeStoresTransitionAndEvent(t *testing.T) {
+	ctx := context.Background()
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	rec, _ := New("a1", "ALARM", time.Now()).Record()
+	alarms.Put(ctx, rec)
+
+	if _, err := Change(ctx, alarms, events, "a1", ActionResolve, "admin", "fixed"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Change(ctx, alarms, events, "a1", ActionResolve, "admin", ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected second resolve to fail, got %v", err)
+	}
+	if _, err := Change(ctx, alarms, events, "missing", ActionResolve, "admin", ""); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected missing alarm to fail with ErrNotFound, got %v", err)
+	}
+
+	stored, _ := alarms.Get(ctx, "a1")
+	if doc, _ := Decode(stored); doc.Status() != StatusResolved {
+		t.Fatalf("expected stored alarm to be resolved, got %s", doc.Status())
+	}
+	history, _ := events.Events(ctx, store.EventQuery{})
+	if len(history.Events) != 1 || history.Events[0].Type != store.EventResolve || history.Events[0].Message != "fixed" {
+		t.Fatalf("expected one resolve event, got %+v", history.Events)
+	}
+}
+
+func TestAddCommentKeepsThreadAcrossRecurrence(t *testing.T) {
+	ctx := context.Background()
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	rec, _ := New("a1", "INSIGHTS", time.Now()).Record()
+	alarms.Put(ctx, rec)
+
+	if _, err := AddComment(ctx, alarms, events, "missing", "analyst", "note"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing alarm, got %v", err)
+	}
+	AddComment(ctx, alarms, events, "a1", "analyst", "checked the host")
+	AddComment(ctx, alarms, events, "a1", "admin", "false positive")
+
+	rec, _ = alarms.Get(ctx, "a1")
+	doc, _ := Decode(rec)
+	if len(doc.Comments) != 2 || doc.Comments[0].By != "analyst" || doc.Comments[1].Text != "false positive" {
+		t.Fatalf("expected two comments in order, got %+v", doc.Comments)
+	}
+
+	history, _ := events.Events(ctx, store.EventQuery{})
+	if len(history.Events) != 2 || history.Events[0].Type != store.EventComment || history.Events[0].Actor != "admin" {
+		t.Fatalf("expected two comment events, got %+v", history.Events)
+	}
+
+	doc.Apply(ActionResolve, "admin", "", time.Now())
+	next := New("a2", "INSIGHTS", time.Now())
+	next.Recur(doc, "syslog")
+	if len(next.Comments) != 2 {
+		t.Fatalf("expected comments to carry over on recurrence, got %+v", next.Comments)
+	}
+}
+
+func TestAssignReportsChanges(t *testing.T) {
+	ctx := context.Background()
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	rec, _ := New("a1", "ALARM", time.Now()).Record()
+	alarms.Put(ctx, rec)
+
+	if doc, changed, err := Assign(ctx, alarms, events, "a1", "alice", "admin"); err != nil || !changed || doc.Assignee != "alice" {
+		t.Fatalf("expected alarm assigned to alice, got %+v, %v, %v", doc, changed, err)
+	}
+	if _, changed, err := Assign(ctx, alarms, events, "a1", "alice", "admin"); err != nil || changed {
+		t.Fatalf("expected a repeated assignment to be a no-op, got %v, %v", changed, err)
+	}
+	if doc, changed, _ := Assign(ctx, alarms, events, "a1", "", "alice"); !changed || doc.Assignee != "" {
+		t.Fatalf("expected alarm to be unassigned, got %+v", doc)
+	}
+
+	history, _ := events.Events(ctx, store.EventQuery{})
+	if len(history.Events) != 2 || history.Events[0].Message != "Unassigned" || history.Events[1].Message != "Assigned to alice" {
+		t.Fatalf("expected assign and unassign events, got %+v", history.Events)
+	}
+}
+
+// racingStore lets another writer change the alarm right after the first Get,
+// as a concurrent action between update's read and write would.
+type racingStore struct {
+	*store.MemoryStore
+	race func()
+}
+
+func (s *racingStore) Get(ctx context.Context, id string) (store.Record, error) {
+	rec, err := s.MemoryStore.Get(ctx, id)
+	if race := s.race; race != nil {
+		s.race = nil
+		race()
+	}
+	return rec, err
+}
+
+func TestUpdateRetriesOnConcurrentChange(t *testing.T) {
+	ctx := context.Background()
+	alarms := &racingStore{MemoryStore: store.NewMemoryStore()}
+	events := store.NewMemoryEventLog(0)
+	rec, _ := New("a1", "INSIGHTS", time.Now()).Record()
+	alarms.Put(ctx, rec)
+
+	alarms.race = func() {
+		if _, err := Change(ctx, alarms.MemoryStore, events, "a1", ActionAck, "admin", ""); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if _, err := AddComment(ctx, alarms, events, "a1", "analyst", "checked the host"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec, _ = alarms.Get(ctx, "a1")
+	doc, _ := Decode(rec)
+	if doc.Status() != StatusAcknowledged || len(doc.Comments) != 1 {
+		t.Fatalf("expected both the ack and the comment to be kept, got %s with %+v", doc.Status(), doc.Comments)
+	}
+}
+
+func TestEscalateSkipsAlarmAcknowledgedSinceRead(t *testing.T) {
+	ctx := context.Background()
+	alarms := &racingStore{MemoryStore: store.NewMemoryStore()}
+	events := store.NewMemoryEventLog(0)
+	rec, _ := New("a1", "INSIGHTS", time.Now()).Record()
+	alarms.Put(ctx, rec)
+
+	alarms.race = func() {
+		Change(ctx, alarms.MemoryStore, events, "a1", ActionAck, "admin", "")
+	}
+	if _, ok, err := Escalate(ctx, alarms, events, "a1", Escalation{Policy: "p", Level: 1, Target: "pager", At: time.Now()}); ok || err != nil {
+		t.Fatalf("expected no escalation of an alarm acknowledged meanwhile, got %v, %v", ok, err)
+	}
+	rec, _ = alarms.Get(ctx, "a1")
+	if doc, _ := Decode(rec); doc.Status() != StatusAcknowledged || doc.EscalationLevel() != 0 {
+		t.Fatalf("expected the acknowledged alarm without escalations, got %s level %d", doc.Status(), doc.EscalationLevel())
+	}
+}
+
+func TestAssignOwnKeepsConcurrentClaim(t *testing.T) {
+	ctx := context.Background()
+	alarms := &racingStore{MemoryStore: store.NewMemoryStore()}
+	events := store.NewMemoryEventLog(0)
+	rec, _ := New("a1", "INSIGHTS", time.Now()).Record()
+	alarms.Put(ctx, rec)
+
+	alarms.race = func() {
+		if _, _, err := Assign(ctx, alarms.MemoryStore, events, "a1", "bob", "bob"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if _, _, err := AssignOwn(ctx, alarms, events, "a1", "alice", "alice"); !errors.Is(err, ErrClaimed) {
+		t.Fatalf("expected ErrClaimed, got %v", err)
+	}
+	if _, _, err := AssignOwn(ctx, alarms, events, "a1", "", "alice"); !errors.Is(err, ErrClaimed) {
+		t.Fatalf("expected releasing someone else's claim to fail, got %v", err)
+	}
+	if _, changed, err := AssignOwn(ctx, alarms, events, "a1", "", "bob"); err != nil || !changed {
+		t.Fatalf("expected bob to release the claim, got %v, %v", changed, err)
+	}
+
+	rec, _ = alarms.Get(ctx, "a1")
+	if doc, _ := Decode(rec); doc.Assignee != "" {
+		t.Fatalf("expected the alarm to be unassigned, got %q", doc.Assignee)
+	}
+}
@@ -0,0 +1,101 @@
+package alarm
+
+import (
+	"context"
+	"fmt"
+
+	"logvault/store"
+)
+
+// UpgradingStore wraps an AlarmStore so that every record it returns holds a
+// current-version Document, whatever release wrote it. Put and Dump pass
+// through unchanged.
+type UpgradingStore struct {
+	store.AlarmStore
+}
+
+// UpgradeOnRead wraps s in an UpgradingStore.
+func UpgradeOnRead(s store.AlarmStore) *UpgradingStore {
+	return &UpgradingStore{AlarmStore: s}
+}
+
+func (u *UpgradingStore) Get(ctx context.Context, id string) (store.Record, error) {
+	rec, err := u.AlarmStore.Get(ctx, id)
+	if err != nil {
+		return rec, err
+	}
+	return upgradeRecord(rec), nil
+}
+
+// Swap compares old, which may be an upgraded record read from u, with the
+// upgraded form of the stored alarm, and swaps against what is stored.
+func (u *UpgradingStore) Swap(ctx context.Context, old, rec store.Record) error {
+	stored, err := u.AlarmStore.Get(ctx, old.ID)
+	if err != nil {
+		return err
+	}
+	if upgradeRecord(stored).Value != old.Value {
+		return store.ErrConflict
+	}
+	return u.AlarmStore.Swap(ctx, stored, rec)
+}
+
+func (u *UpgradingStore) List(ctx context.Context) ([]store.Record, error) {
+	page, err := u.Query(ctx, store.Query{})
+	return page.Records, err
+}
+
+func (u *UpgradingStore) Query(ctx context.Context, q store.Query) (store.Page, error) {
+	page, err := u.AlarmStore.Query(ctx, q)
+	for i, rec := range page.Records {
+		page.Records[i] = upgradeRecord(rec)
+	}
+	return page, err
+}
+
+func upgradeRecord(rec store.Record) store.Record {
+	doc, upgraded := Decode(rec)
+	if !upgraded {
+		return rec
+	}
+	if upgradedRec, err := doc.Record(); err == nil {
+		return upgradedRec
+	}
+	return rec
+}
+
+// Migrate rewrites every stored alarm that is not at the current schema
+// version. It returns how many alarms were examined and how many were, or
+// with dryRun would be, rewritten.
+func Migrate(ctx context.Context, s store.AlarmStore, dryRun bool) (int, int, error) {
+	total, upgraded := 0, 0
+	q := store.Query{Limit: 1000}
+	for {
+		page, err := s.Query(ctx, q)
+		if err != nil {
+			return total, upgraded, fmt.Errorf("failed to read alarms: %w", err)
+		}
+		for _, rec := range page.Records {
+			total++
+			doc, needed := Decode(rec)
+			if !needed {
+				continue
+			}
+			upgraded++
+			if dryRun {
+				continue
+			}
+			newRec, err := doc.Record()
+			if err != nil {
+				return total, upgraded, err
+			}
+			if err := s.Put(ctx, newRec); err != nil {
+				return total, upgraded, fmt.Errorf("failed to rewrite %s: %w", store.Key(rec.ID), err)
+			}
+		}
+		if page.NextCursor == "" {
+			return total, upgraded, nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
@@ -0,0 +1,275 @@
+package archive
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const filePrefix = "logvault-"
+const fileSuffix = ".ndjson.gz"
+const dayLayout = "2006-01-02"
+
+// Entry is one archived syslog event: the raw message as received plus the
+// alarm document it was parsed into, when there is one.
+type Entry struct {
+	Time     time.Time       `json:"time"`
+	Source   string          `json:"source,omitempty"`
+	Hostname string          `json:"hostname,omitempty"`
+	Tag      string          `json:"tag"`
+	Severity string          `json:"severity,omitempty"`
+	Raw      string          `json:"raw"`
+	AlarmID  string          `json:"alarm_id,omitempty"`
+	Parsed   json.RawMessage `json:"parsed,omitempty"`
+}
+
+// Writer appends entries to gzip-compressed NDJSON files, one per UTC day
+// and process start, and removes old files by age and total size. A nil *Writer discards entries,
+// so callers do not need to check whether archiving is enabled.
+type Writer struct {
+	dir      string
+	maxAge   time.Duration
+	maxBytes int64
+
+	mu   sync.Mutex
+	day  string
+	name string
+	file *os.File
+	gz   *gzip.Writer
+}
+
+// Open prepares dir for archiving. A zero maxAge or maxBytes disables that
+// cleanup limit.
+func Open(dir string, maxAge time.Duration, maxBytes int64) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	w := &Writer{dir: dir, maxAge: maxAge, maxBytes: maxBytes}
+	w.cleanup(time.Now())
+	return w, nil
+}
+
+// FileName returns the name of the first archive file for the UTC day of t.
+func FileName(t time.Time) string {
+	return segmentName(t, 0)
+}
+
+// segmentName returns the name of the nth archive file for the UTC day of
+// t: logvault-YYYY-MM-DD.ndjson.gz, then logvault-YYYY-MM-DD.<n>.ndjson.gz.
+func segmentName(t time.Time, n int) string {
+	if n == 0 {
+		return filePrefix + t.UTC().Format(dayLayout) + fileSuffix
+	}
+	return fmt.Sprintf("%s%s.%d%s", filePrefix, t.UTC().Format(dayLayout), n, fileSuffix)
+}
+
+// Write appends an entry to the file for the entry's day, rotating when the
+// day changes. Data reaches disk on Flush, Close or rotation.
+func (w *Writer) Write(e Entry) error {
+	if w == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	day := e.Time.UTC().Format(dayLayout)
+	if day != w.day {
+		if err := w.rotate(e.Time); err != nil {
+			return err
+		}
+	}
+	_, err = w.gz.Write(line)
+	return err
+}
+
+// rotate closes the current file and creates a new one for t. Existing
+// files are never appended to: after an unclean shutdown their last gzip
+// member is unfinished, and a member written after it could not be read.
+func (w *Writer) rotate(t time.Time) error {
+	if err := w.closeFile(); err != nil {
+		log.Printf("Failed to close archive file: %v", err)
+	}
+
+	var file *os.File
+	var name string
+	for n := 0; ; n++ {
+		name = segmentName(t, n)
+		f, err := os.OpenFile(filepath.Join(w.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to open archive file: %w", err)
+		}
+		file = f
+		break
+	}
+
+	w.file = file
+	w.name = name
+	w.gz = gzip.NewWriter(file)
+	w.day = t.UTC().Format(dayLayout)
+	w.cleanup(t)
+	return nil
+}
+
+func (w *Writer) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	gzErr := w.gz.Close()
+	fileErr := w.file.Close()
+	w.file, w.gz, w.day, w.name = nil, nil, "", ""
+	if gzErr != nil {
+		return gzErr
+	}
+	return fileErr
+}
+
+// Flush writes buffered entries to the current file.
+func (w *Writer) Flush() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.gz == nil {
+		return nil
+	}
+	return w.gz.Flush()
+}
+
+// Close flushes and closes the current file.
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeFile()
+}
+
+// Run flushes the archive every interval. It never returns.
+func (w *Writer) Run(interval time.Duration) {
+	if w == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := w.Flush(); err != nil {
+			log.Printf("Failed to flush archive: %v", err)
+		}
+	}
+}
+
+// cleanup deletes archive files older than maxAge, then the oldest files
+// until the directory is within maxBytes. The current day's files are kept
+// from age cleanup and the file being written from size cleanup.
+func (w *Writer) cleanup(now time.Time) {
+	files, err := listFiles(w.dir)
+	if err != nil {
+		log.Printf("Failed to list archive files: %v", err)
+		return
+	}
+
+	var total int64
+	kept := files[:0]
+	for _, f := range files {
+		if w.maxAge > 0 && f.day.Before(now.UTC().Add(-w.maxAge).Truncate(24*time.Hour)) && !f.day.Equal(now.UTC().Truncate(24*time.Hour)) {
+			w.remove(f)
+			continue
+		}
+		total += f.size
+		kept = append(kept, f)
+	}
+
+	for _, f := range kept {
+		if w.maxBytes <= 0 || total <= w.maxBytes {
+			break
+		}
+		if f.name == w.name {
+			continue
+		}
+		w.remove(f)
+		total -= f.size
+	}
+}
+
+func (w *Writer) remove(f archiveFile) {
+	if err := os.Remove(filepath.Join(w.dir, f.name)); err != nil {
+		log.Printf("Failed to remove archive file %s: %v", f.name, err)
+		return
+	}
+	log.Printf("ARCHIVE: Removed %s", f.name)
+}
+
+type archiveFile struct {
+	name    string
+	day     time.Time
+	segment int
+	size    int64
+}
+
+// listFiles returns the archive files in dir, oldest first, with a day's
+// files in the order they were written.
+func listFiles(dir string) ([]archiveFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []archiveFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		dayPart, segmentPart, hasSegment := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix), ".")
+		day, err := time.Parse(dayLayout, dayPart)
+		if err != nil {
+			continue
+		}
+		segment := 0
+		if hasSegment {
+			if segment, err = strconv.Atoi(segmentPart); err != nil || segment < 1 {
+				continue
+			}
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, archiveFile{name: name, day: day, segment: segment, size: info.Size()})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].day.Equal(files[j].day) {
+			return files[i].day.Before(files[j].day)
+		}
+		return files[i].segment < files[j].segment
+	})
+	return files, nil
+}
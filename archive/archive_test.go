@@ -0,0 +1,205 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func collect(t *testing.T, dir string, filter Filter) []Entry {
+	t.Helper()
+
+	var entries []Entry
+	if err := Search(dir, filter, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	}); err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	return entries
+}
+
+func TestWriterRotatesDailyAndSearchFilters(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	day1 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	entries := []Entry{
+		{Time: day1, Source: "10.0.0.1", Tag: "ALARM", Raw: "disk full", AlarmID: "a1", Parsed: json.RawMessage(`{"tag":"ALARM","message":"disk full"}`)},
+		{Time: day1.Add(time.Hour), Source: "10.0.0.2", Tag: "INSIGHTS", Raw: "x`y", AlarmID: "a2", Parsed: json.RawMessage(`{"v":2,"tag":"INSIGHTS","fields":{"RuleName":"R1"}}`)},
+		{Time: day2, Source: "10.0.0.1", Tag: "ALARM", Raw: "disk ok"},
+	}
+	for _, e := range entries {
+		if err := w.Write(e); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	for _, day := range []time.Time{day1, day2} {
+		if _, err := os.Stat(filepath.Join(dir, FileName(day))); err != nil {
+			t.Fatalf("expected archive file for %s: %v", day.Format("2006-01-02"), err)
+		}
+	}
+
+	if got := collect(t, dir, Filter{}); len(got) != 3 {
+		t.Fatalf("expected 3 archived entries, got %d", len(got))
+	}
+	if got := collect(t, dir, Filter{From: day2}); len(got) != 1 || got[0].Raw != "disk ok" {
+		t.Fatalf("expected only the second day's entry, got %+v", got)
+	}
+	if got := collect(t, dir, Filter{Fields: map[string]string{"source": "10.0.0.1", "tag": "alarm"}}); len(got) != 2 {
+		t.Fatalf("expected 2 entries from 10.0.0.1, got %d", len(got))
+	}
+	if got := collect(t, dir, Filter{Fields: map[string]string{"RuleName": "R1"}}); len(got) != 1 || got[0].AlarmID != "a2" {
+		t.Fatalf("expected parsed field match on a2, got %+v", got)
+	}
+}
+
+func TestWriterStartsNewFileOnEachOpen(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+
+	for i := 0; i < 2; i++ {
+		w, err := Open(dir, 0, 0)
+		if err != nil {
+			t.Fatalf("Open returned error: %v", err)
+		}
+		if err := w.Write(Entry{Time: now, Tag: "ALARM", Raw: "restart"}); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+	}
+
+	for _, name := range []string{FileName(now), segmentName(now, 1)} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("expected archive file %s: %v", name, err)
+		}
+	}
+	if got := collect(t, dir, Filter{}); len(got) != 2 {
+		t.Fatalf("expected entries from both files, got %d", len(got))
+	}
+}
+
+func TestSearchReadsEntriesWrittenAroundAnUncleanShutdown(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+
+	crashed, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if err := crashed.Write(Entry{Time: now, Tag: "ALARM", Raw: "before crash"}); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := crashed.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+	crashed.file.Close() // Never closed cleanly
+
+	w, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if err := w.Write(Entry{Time: now, Tag: "ALARM", Raw: "after restart"}); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	got := collect(t, dir, Filter{})
+	if len(got) != 2 || got[0].Raw != "before crash" || got[1].Raw != "after restart" {
+		t.Fatalf("expected both entries in order, got %+v", got)
+	}
+}
+
+func TestSearchSkipsCorruptTrailingMember(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+
+	// An unfinished gzip member followed by another one, as older versions
+	// wrote by appending to a file after an unclean shutdown.
+	var data bytes.Buffer
+	unfinished := gzip.NewWriter(&data)
+	unfinished.Write([]byte(`{"time":"` + now.Format(time.RFC3339Nano) + `","tag":"ALARM","raw":"readable"}` + "\n"))
+	unfinished.Flush()
+	appended := gzip.NewWriter(&data)
+	appended.Write([]byte(`{"time":"` + now.Format(time.RFC3339Nano) + `","tag":"ALARM","raw":"lost"}` + "\n"))
+	appended.Close()
+	if err := os.WriteFile(filepath.Join(dir, FileName(now)), data.Bytes(), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	got := collect(t, dir, Filter{})
+	if len(got) != 1 || got[0].Raw != "readable" {
+		t.Fatalf("expected the entries before the corrupt member, got %+v", got)
+	}
+}
+
+func TestSearchReadsFlushedEntriesFromOpenFile(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.Write(Entry{Time: time.Now(), Tag: "ALARM", Raw: "live"}); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+
+	if got := collect(t, dir, Filter{}); len(got) != 1 {
+		t.Fatalf("expected flushed entry to be readable, got %d", len(got))
+	}
+}
+
+func TestCleanupRemovesOldAndOversizedFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	for _, age := range []int{40, 3, 2, 1} {
+		name := filepath.Join(dir, FileName(now.AddDate(0, 0, -age)))
+		if err := os.WriteFile(name, make([]byte, 100), 0o640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := &Writer{dir: dir, maxAge: 30 * 24 * time.Hour, maxBytes: 250}
+	w.cleanup(now)
+
+	files, err := listFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files to remain, got %d", len(files))
+	}
+	if files[0].name != FileName(now.AddDate(0, 0, -2)) {
+		t.Fatalf("expected oldest files to be removed first, kept %s", files[0].name)
+	}
+}
+
+func TestNilWriterDiscardsEntries(t *testing.T) {
+	var w *Writer
+	if err := w.Write(Entry{Time: time.Now()}); err != nil {
+		t.Fatalf("expected nil writer to discard entries, got %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("expected nil writer Close to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrStop can be returned from a Search callback to end the search early.
+var ErrStop = errors.New("stop search")
+
+// Filter selects archived entries by time range and exact field values.
+// Fields are matched against the entry itself (source, hostname, tag,
+// severity, alarm_id, raw) and then against the parsed alarm document and its
+// "fields" object.
+type Filter struct {
+	From   time.Time
+	To     time.Time
+	Fields map[string]string
+}
+
+// Matches reports whether e satisfies the filter.
+func (f Filter) Matches(e Entry) bool {
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && e.Time.After(f.To) {
+		return false
+	}
+	if len(f.Fields) == 0 {
+		return true
+	}
+
+	var parsed map[string]interface{}
+	if len(e.Parsed) > 0 {
+		json.Unmarshal(e.Parsed, &parsed)
+	}
+	for field, want := range f.Fields {
+		if !strings.EqualFold(entryField(e, parsed, field), want) {
+			return false
+		}
+	}
+	return true
+}
+
+func entryField(e Entry, parsed map[string]interface{}, field string) string {
+	switch field {
+	case "source":
+		return e.Source
+	case "hostname":
+		return e.Hostname
+	case "tag":
+		return e.Tag
+	case "severity":
+		return e.Severity
+	case "alarm_id":
+		return e.AlarmID
+	case "raw":
+		return e.Raw
+	}
+	v, ok := parsed[field]
+	if !ok {
+		nested, _ := parsed["fields"].(map[string]interface{})
+		v = nested[field]
+	}
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Search reads the archive files in dir that can hold entries within the
+// filter's time range, oldest first, and calls fn for every matching entry.
+// Lines that cannot be decoded are skipped.
+func Search(dir string, filter Filter, fn func(Entry) error) error {
+	files, err := listFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if !filter.From.IsZero() && f.day.Add(24*time.Hour).Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && f.day.After(filter.To) {
+			continue
+		}
+		if err := searchFile(filepath.Join(dir, f.name), filter, fn); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// searchFile calls fn for the matching entries of one archive file. A file
+// whose gzip data ends early or is corrupt, e.g. after an unclean shutdown,
+// yields the entries before the damage; the rest is logged and skipped.
+func searchFile(path string, filter Filter, fn func(Entry) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			log.Printf("Skipping unreadable archive file %s: %v", path, err)
+		}
+		return nil // Empty or unreadable file
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if !filter.Matches(e) {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	// The file being written, or one left by a crash, ends in an unfinished
+	// gzip member, which reads as an unexpected EOF.
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		log.Printf("Skipping the rest of archive file %s after a corrupt entry: %v", path, err)
+	}
+	return nil
+}
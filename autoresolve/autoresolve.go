@@ -0,0 +1,259 @@
+package autoresolve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/notifier"
+	"logvault/store"
+)
+
+const sweepPageSize = 1000
+
+// reopenReadOverlap is how far each sweep rereads the event log before the
+// previous one stopped. Event times have millisecond resolution and, with
+// Redis, come from the server's clock, so a reopen may be stamped slightly
+// before the sweep that should have seen it.
+const reopenReadOverlap = time.Minute
+
+type rule struct {
+	tag   string
+	after time.Duration
+}
+
+// Resolver resolves open and acknowledged alarms that have not recurred
+// within the inactivity period configured for their tag. An alarm's last
+// activity is its received time, which moves forward when it recurs, or the
+// time it was last reopened by hand, whichever is later.
+type Resolver struct {
+	alarms    store.AlarmStore
+	events    store.EventLog
+	appConfig config.Config
+	rules     []rule
+
+	// checked is, per rule, the cutoff of the last successful sweep. Alarms
+	// received before it were already considered, so later sweeps only look
+	// at alarms that crossed the cutoff since.
+	checked []time.Time
+	// pending is, per rule, the IDs of active alarms that were reopened by
+	// hand and so are not yet inactive even though their received time may
+	// be behind checked. They are found in the event log and rechecked on
+	// every sweep until they are resolved or no longer active.
+	pending []map[string]struct{}
+	// reopensRead is the time up to which reopen events were read.
+	reopensRead time.Time
+}
+
+// NewResolver builds a resolver from the auto_resolve config block.
+func NewResolver(alarms store.AlarmStore, events store.EventLog, appConfig config.Config) *Resolver {
+	r := &Resolver{alarms: alarms, events: events, appConfig: appConfig}
+	for _, cfg := range appConfig.AutoResolve.Rules {
+		tag := strings.TrimSpace(cfg.Tag)
+		if tag == "" || cfg.After <= 0 {
+			log.Printf("Ignoring auto_resolve rule %q: tag and a positive after are required", cfg.Tag)
+			continue
+		}
+		r.rules = append(r.rules, rule{tag: tag, after: cfg.After})
+	}
+	r.checked = make([]time.Time, len(r.rules))
+	r.pending = make([]map[string]struct{}, len(r.rules))
+	for i := range r.pending {
+		r.pending[i] = make(map[string]struct{})
+	}
+	return r
+}
+
+// Run resolves inactive alarms every interval. It returns immediately when
+// no rules are configured.
+func (r *Resolver) Run(interval time.Duration) {
+	if len(r.rules) == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		resolved, err := r.Sweep(context.Background(), time.Now())
+		if err != nil {
+			log.Printf("Auto-resolve sweep failed: %v", err)
+		}
+		if resolved > 0 {
+			log.Printf("AUTO-RESOLVE: Resolved %d inactive alarms", resolved)
+		}
+	}
+}
+
+// Sweep resolves every active alarm whose last activity is older than its
+// rule's inactivity period at now, and returns how many were resolved.
+func (r *Resolver) Sweep(ctx context.Context, now time.Time) (int, error) {
+	if err := r.readReopens(ctx, now); err != nil {
+		return 0, fmt.Errorf("reading reopened alarms: %w", err)
+	}
+
+	resolved := 0
+	for i, rl := range r.rules {
+		cutoff := now.Add(-rl.after)
+		n, err := r.sweepPending(ctx, i, cutoff)
+		resolved += n
+		if err != nil {
+			return resolved, fmt.Errorf("rule for tag %s: %w", rl.tag, err)
+		}
+		n, err = r.sweepRule(ctx, i, r.checked[i], cutoff)
+		resolved += n
+		if err != nil {
+			return resolved, fmt.Errorf("rule for tag %s: %w", rl.tag, err)
+		}
+		r.checked[i] = cutoff
+	}
+	return resolved, nil
+}
+
+// readReopens adds the alarms reopened since the previous sweep to the
+// pending set of their rule. Reopens read twice are harmless. The first sweep reads back as far as the
+// longest rule period, as alarms reopened before that are already inactive
+// and found by their received time.
+func (r *Resolver) readReopens(ctx context.Context, now time.Time) error {
+	since := r.reopensRead.Add(-reopenReadOverlap)
+	if r.reopensRead.IsZero() {
+		var longest time.Duration
+		for _, rl := range r.rules {
+			if rl.after > longest {
+				longest = rl.after
+			}
+		}
+		since = now.Add(-longest)
+	}
+
+	q := store.EventQuery{Since: since, Until: now, Limit: sweepPageSize}
+	for {
+		page, err := r.events.Events(ctx, q)
+		if err != nil {
+			return err
+		}
+		for _, e := range page.Events {
+			if e.Type != store.EventReopen {
+				continue
+			}
+			for i, rl := range r.rules {
+				if strings.EqualFold(e.Tag, rl.tag) {
+					r.pending[i][e.AlarmID] = struct{}{}
+				}
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Before = page.NextCursor
+	}
+	r.reopensRead = now
+	return nil
+}
+
+// sweepPending rechecks the reopened alarms of rule i, dropping those that
+// were resolved or are no longer active.
+func (r *Resolver) sweepPending(ctx context.Context, i int, cutoff time.Time) (int, error) {
+	resolved := 0
+	for id := range r.pending[i] {
+		rec, err := r.alarms.Get(ctx, id)
+		if errors.Is(err, store.ErrNotFound) {
+			delete(r.pending[i], id)
+			continue
+		}
+		if err != nil {
+			return resolved, err
+		}
+		ok, err := r.resolveInactive(ctx, i, rec, cutoff)
+		if err != nil {
+			return resolved, err
+		}
+		if ok {
+			resolved++
+		}
+	}
+	return resolved, nil
+}
+
+func (r *Resolver) sweepRule(ctx context.Context, i int, since, cutoff time.Time) (int, error) {
+	resolved := 0
+	q := store.Query{Tag: r.rules[i].tag, Since: since, Until: cutoff, Limit: sweepPageSize}
+	for {
+		page, err := r.alarms.Query(ctx, q)
+		if err != nil {
+			return resolved, err
+		}
+
+		for _, rec := range page.Records {
+			ok, err := r.resolveInactive(ctx, i, rec, cutoff)
+			if err != nil {
+				return resolved, err
+			}
+			if ok {
+				resolved++
+			}
+		}
+
+		if page.NextCursor == "" {
+			return resolved, nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+// resolveInactive resolves rec if it is active and its last activity is at
+// or before cutoff, and reports whether it did. An active alarm that was
+// reopened after cutoff is kept pending for rule i instead.
+func (r *Resolver) resolveInactive(ctx context.Context, i int, rec store.Record, cutoff time.Time) (bool, error) {
+	doc, _ := alarm.Decode(rec)
+	if !doc.Active() {
+		delete(r.pending[i], rec.ID)
+		return false, nil
+	}
+	if lastActivity(doc).After(cutoff) {
+		r.pending[i][rec.ID] = struct{}{}
+		return false, nil
+	}
+	delete(r.pending[i], rec.ID)
+
+	reason := fmt.Sprintf("No repeat within %s", r.rules[i].after)
+	doc, err := alarm.Change(ctx, r.alarms, r.events, rec.ID, alarm.ActionResolve, "auto-resolve", reason)
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, alarm.ErrInvalidTransition) {
+		return false, nil // Deleted or changed since it was read
+	}
+	if err != nil {
+		return false, err
+	}
+	r.notify(doc, reason)
+	return true, nil
+}
+
+// lastActivity is when doc was last received or reopened.
+func lastActivity(doc alarm.Document) time.Time {
+	last := doc.ReceivedAt
+	for _, t := range doc.State.History {
+		if t.Action == store.EventReopen && t.At.After(last) {
+			last = t.At
+		}
+	}
+	return last
+}
+
+func (r *Resolver) notify(doc alarm.Document, reason string) {
+	// A silenced alarm was never announced, so neither is its resolution.
+	if doc.Suppressed() || !r.appConfig.ExternalAPI.Enabled || !notifier.ShouldTrigger(doc.Tag, r.appConfig.ExternalAPI.TriggerTags) {
+		return
+	}
+	go notifier.CallExternalAPI(r.appConfig, map[string]string{
+		"key":     store.Key(doc.ID),
+		"message": reason,
+		"status":  "CLEAR",
+	})
+}
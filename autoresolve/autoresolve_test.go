@@ -0,0 +1,123 @@
+package autoresolve
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/store"
+)
+
+func newTestConfig() config.Config {
+	cfg := config.Config{}
+	cfg.AutoResolve.Rules = []struct {
+		Tag   string        `mapstructure:"tag"`
+		After time.Duration `mapstructure:"after"`
+	}{
+		{Tag: "LINK_DOWN", After: 30 * time.Minute},
+	}
+	return cfg
+}
+
+func TestSweepResolvesInactiveAlarms(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+
+	put := func(id, tag string, age time.Duration, status string) {
+		doc := alarm.New(id, tag, now.Add(-age))
+		doc.State.Status = status
+		rec, _ := doc.Record()
+		alarms.Put(ctx, rec)
+	}
+	put("stale", "LINK_DOWN", time.Hour, alarm.StatusOpen)
+	put("stale-acked", "link_down", time.Hour, alarm.StatusAcknowledged)
+	put("recent", "LINK_DOWN", 10*time.Minute, alarm.StatusOpen)
+	put("closed", "LINK_DOWN", time.Hour, alarm.StatusClosed)
+	put("other-tag", "INSIGHTS", time.Hour, alarm.StatusOpen)
+
+	r := NewResolver(alarms, events, newTestConfig())
+	resolved, err := r.Sweep(ctx, now)
+	if err != nil || resolved != 2 {
+		t.Fatalf("expected 2 alarms resolved, got %d, %v", resolved, err)
+	}
+
+	want := map[string]string{
+		"stale":       alarm.StatusResolved,
+		"stale-acked": alarm.StatusResolved,
+		"recent":      alarm.StatusOpen,
+		"closed":      alarm.StatusClosed,
+		"other-tag":   alarm.StatusOpen,
+	}
+	for id, status := range want {
+		rec, _ := alarms.Get(ctx, id)
+		if doc, _ := alarm.Decode(rec); doc.Status() != status {
+			t.Fatalf("%s: expected status %s, got %s", id, status, doc.Status())
+		}
+	}
+
+	rec, _ := alarms.Get(ctx, "stale")
+	doc, _ := alarm.Decode(rec)
+	last := doc.State.History[len(doc.State.History)-1]
+	if last.By != "auto-resolve" || last.Comment != "No repeat within 30m0s" {
+		t.Fatalf("expected the reason to be recorded, got %+v", last)
+	}
+
+	history, _ := events.Events(ctx, store.EventQuery{})
+	if len(history.Events) != 2 || history.Events[0].Type != store.EventResolve {
+		t.Fatalf("expected two resolve events, got %+v", history.Events)
+	}
+}
+
+func TestSweepOnlyChecksAlarmsThatCrossedTheCutoff(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	alarms := store.NewMemoryStore()
+	r := NewResolver(alarms, store.NewMemoryEventLog(0), newTestConfig())
+
+	if _, err := r.Sweep(ctx, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec, _ := alarm.New("later", "LINK_DOWN", now.Add(-10*time.Minute)).Record()
+	alarms.Put(ctx, rec)
+
+	if resolved, _ := r.Sweep(ctx, now.Add(time.Minute)); resolved != 0 {
+		t.Fatalf("expected a recent alarm to stay open, got %d resolved", resolved)
+	}
+	if resolved, _ := r.Sweep(ctx, now.Add(25*time.Minute)); resolved != 1 {
+		t.Fatalf("expected the alarm to be resolved once inactive, got %d resolved", resolved)
+	}
+}
+
+func TestSweepResolvesAlarmsReopenedByHandAgain(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	r := NewResolver(alarms, events, newTestConfig())
+
+	rec, _ := alarm.New("old", "LINK_DOWN", now.Add(-2*time.Hour)).Record()
+	alarms.Put(ctx, rec)
+	if resolved, _ := r.Sweep(ctx, now); resolved != 1 {
+		t.Fatalf("expected the stale alarm to be resolved, got %d resolved", resolved)
+	}
+
+	if _, err := alarm.Change(ctx, alarms, events, "old", alarm.ActionReopen, "alice", "Not fixed"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved, _ := r.Sweep(ctx, now.Add(time.Minute)); resolved != 0 {
+		t.Fatalf("expected a just reopened alarm to stay open, got %d resolved", resolved)
+	}
+	if resolved, _ := r.Sweep(ctx, now.Add(31*time.Minute)); resolved != 1 {
+		t.Fatalf("expected the reopened alarm to be resolved once inactive, got %d resolved", resolved)
+	}
+
+	rec, _ = alarms.Get(ctx, "old")
+	if doc, _ := alarm.Decode(rec); doc.Status() != alarm.StatusResolved {
+		t.Fatalf("expected the alarm to be resolved, got %s", doc.Status())
+	}
+}
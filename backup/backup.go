@@ -0,0 +1,314 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"logvault/store"
+)
+
+// Format and Version identify a Logvault backup. Version is bumped whenever
+// the line layout changes incompatibly; Restore rejects newer versions.
+const (
+	Format  = "logvault-backup"
+	Version = 2
+)
+
+// newestFirstVersion is the last version that listed events newest first.
+const newestFirstVersion = 1
+
+// pageSize is how many alarms are read from the store per query.
+const pageSize = 1000
+
+// ErrConflict is returned by Restore with ConflictFail when an alarm in the
+// backup already exists in the store.
+var ErrConflict = errors.New("alarm already exists")
+
+// Conflict handling for alarms that already exist when restoring.
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictFail      = "fail"
+)
+
+// Header is the first line of a backup.
+type Header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Tags      []string  `json:"tags,omitempty"`
+	Events    bool      `json:"events"`
+}
+
+// line is one alarm or event in a backup, after the header.
+type line struct {
+	Type  string       `json:"type"`
+	Alarm *alarm       `json:"alarm,omitempty"`
+	Event *store.Event `json:"event,omitempty"`
+}
+
+// alarm keeps the indexed fields next to the stored document so Restore can
+// rebuild the time and tag indexes exactly.
+type alarm struct {
+	ID         string    `json:"id"`
+	Tag        string    `json:"tag"`
+	ReceivedAt time.Time `json:"received_at"`
+	Value      string    `json:"value"`
+}
+
+// Options select what is exported or restored. An empty Tags list means every
+// tag; tags match case-insensitively.
+type Options struct {
+	Tags   []string
+	Events bool
+}
+
+func (o Options) allowsTag(tag string) bool {
+	if len(o.Tags) == 0 {
+		return true
+	}
+	for _, t := range o.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// RestoreOptions add conflict handling and dry-run to Options.
+type RestoreOptions struct {
+	Options
+	Conflict string
+	DryRun   bool
+}
+
+// Stats counts what an export or restore handled.
+type Stats struct {
+	Alarms      int
+	Events      int
+	Skipped     int
+	Overwritten int
+}
+
+// Export writes a backup of the alarms, and optionally the event history, to
+// w as NDJSON: a Header line followed by one line per alarm and event.
+func Export(ctx context.Context, w io.Writer, alarms store.AlarmStore, events store.EventLog, opts Options) (Stats, error) {
+	var stats Stats
+	enc := json.NewEncoder(w)
+
+	header := Header{Format: Format, Version: Version, CreatedAt: time.Now().UTC(), Tags: opts.Tags, Events: opts.Events}
+	if err := enc.Encode(header); err != nil {
+		return stats, err
+	}
+
+	tags := opts.Tags
+	if len(tags) == 0 {
+		tags = []string{""}
+	}
+	for _, tag := range tags {
+		q := store.Query{Tag: tag, Limit: pageSize}
+		for {
+			page, err := alarms.Query(ctx, q)
+			if err != nil {
+				return stats, fmt.Errorf("failed to read alarms: %w", err)
+			}
+			for _, rec := range page.Records {
+				a := &alarm{ID: rec.ID, Tag: rec.Tag, ReceivedAt: rec.ReceivedAt, Value: rec.Value}
+				if err := enc.Encode(line{Type: "alarm", Alarm: a}); err != nil {
+					return stats, err
+				}
+				stats.Alarms++
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			q.Cursor = page.NextCursor
+		}
+	}
+
+	if !opts.Events || events == nil {
+		return stats, nil
+	}
+	n, err := exportEvents(ctx, enc, events, opts)
+	stats.Events = n
+	return stats, err
+}
+
+// exportEvents writes the event history oldest first, so Restore appends
+// every alarm's history in its original order. The log is read newest
+// first, so a first pass records where each page starts and a second pass
+// writes the pages in reverse, holding one page at a time.
+func exportEvents(ctx context.Context, enc *json.Encoder, events store.EventLog, opts Options) (int, error) {
+	newest, err := events.Events(ctx, store.EventQuery{Limit: pageSize})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read events: %w", err)
+	}
+	var cursors []string
+	for cursor := newest.NextCursor; cursor != ""; {
+		cursors = append(cursors, cursor)
+		page, err := events.Events(ctx, store.EventQuery{Before: cursor, Limit: pageSize})
+		if err != nil {
+			return 0, fmt.Errorf("failed to read events: %w", err)
+		}
+		cursor = page.NextCursor
+	}
+
+	written := 0
+	write := func(page []store.Event) error {
+		for i := len(page) - 1; i >= 0; i-- {
+			e := page[i]
+			if !opts.allowsTag(e.Tag) {
+				continue
+			}
+			if err := enc.Encode(line{Type: "event", Event: &e}); err != nil {
+				return err
+			}
+			written++
+		}
+		return nil
+	}
+	for i := len(cursors) - 1; i >= 0; i-- {
+		page, err := events.Events(ctx, store.EventQuery{Before: cursors[i], Limit: pageSize})
+		if err != nil {
+			return written, fmt.Errorf("failed to read events: %w", err)
+		}
+		if err := write(page.Events); err != nil {
+			return written, err
+		}
+	}
+	// The newest page was kept from the first pass, so events appended
+	// during the export are left out rather than written out of order.
+	return written, write(newest.Events)
+}
+
+// Restore reads a backup from r and writes its alarms, and with opts.Events
+// its events, to the store. Existing alarms are handled per opts.Conflict.
+// Events are appended in file order, which is oldest first. Restored events
+// get new IDs and times from the event log; their original time is kept in
+// OccurredAt and noted in the message. With opts.DryRun nothing is written.
+func Restore(ctx context.Context, r io.Reader, alarms store.AlarmStore, events store.EventLog, opts RestoreOptions) (Stats, error) {
+	var stats Stats
+	switch opts.Conflict {
+	case "":
+		opts.Conflict = ConflictSkip
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return stats, fmt.Errorf("unknown conflict mode %q", opts.Conflict)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return stats, err
+		}
+		return stats, fmt.Errorf("backup is empty")
+	}
+	var header Header
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Format != Format {
+		return stats, fmt.Errorf("not a Logvault backup")
+	}
+	if header.Version > Version {
+		return stats, fmt.Errorf("backup version %d is newer than supported version %d", header.Version, Version)
+	}
+
+	// Older backups list events newest first; they are held back and
+	// appended in reverse once the file is read.
+	var reversed []store.Event
+	lineNo := 1
+	for scanner.Scan() {
+		lineNo++
+		var l line
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			return stats, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		switch {
+		case l.Type == "alarm" && l.Alarm != nil:
+			if !opts.allowsTag(l.Alarm.Tag) {
+				continue
+			}
+			if err := restoreAlarm(ctx, alarms, *l.Alarm, opts, &stats); err != nil {
+				return stats, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+		case l.Type == "event" && l.Event != nil:
+			if !opts.Events || !opts.allowsTag(l.Event.Tag) {
+				continue
+			}
+			if !opts.DryRun {
+				e := restoredEvent(*l.Event)
+				if header.Version <= newestFirstVersion {
+					reversed = append(reversed, e)
+				} else if err := events.Append(ctx, e); err != nil {
+					return stats, fmt.Errorf("line %d: failed to restore event: %w", lineNo, err)
+				}
+			}
+			stats.Events++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, err
+	}
+	for i := len(reversed) - 1; i >= 0; i-- {
+		if err := events.Append(ctx, reversed[i]); err != nil {
+			return stats, fmt.Errorf("failed to restore event: %w", err)
+		}
+	}
+	return stats, nil
+}
+
+// restoredEvent prepares a backed up event to be appended again: the log
+// assigns a new ID and time, so the original time moves to OccurredAt.
+// Events that were already restored once keep their first original time.
+func restoredEvent(e store.Event) store.Event {
+	if e.OccurredAt == nil {
+		occurred := e.Time
+		e.OccurredAt = &occurred
+		e.Message = restoredEventMessage(e)
+	}
+	e.ID, e.Time = "", time.Time{}
+	return e
+}
+
+func restoreAlarm(ctx context.Context, alarms store.AlarmStore, a alarm, opts RestoreOptions, stats *Stats) error {
+	_, err := alarms.Get(ctx, a.ID)
+	exists := err == nil
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("failed to check %s: %w", store.Key(a.ID), err)
+	}
+
+	if exists {
+		switch opts.Conflict {
+		case ConflictSkip:
+			stats.Skipped++
+			return nil
+		case ConflictFail:
+			return fmt.Errorf("%s: %w", store.Key(a.ID), ErrConflict)
+		}
+		stats.Overwritten++
+	}
+
+	if !opts.DryRun {
+		rec := store.Record{ID: a.ID, Tag: a.Tag, ReceivedAt: a.ReceivedAt, Value: a.Value}
+		if err := alarms.Put(ctx, rec); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", store.Key(a.ID), err)
+		}
+	}
+	stats.Alarms++
+	return nil
+}
+
+func restoredEventMessage(e store.Event) string {
+	note := "restored from backup, originally " + e.OccurredAt.UTC().Format(time.RFC3339)
+	if e.Message == "" {
+		return note
+	}
+	return e.Message + " (" + note + ")"
+}
@@ -0,0 +1,179 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"logvault/store"
+)
+
+func seededStore(t *testing.T) (*store.MemoryStore, *store.MemoryEventLog) {
+	t.Helper()
+
+	ctx := context.Background()
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, tag := range []string{"ALARM", "INSIGHTS", "ALARM"} {
+		id := string(rune('a' + i))
+		alarms.Put(ctx, store.Record{ID: id, Tag: tag, ReceivedAt: base.Add(time.Duration(i) * time.Minute), Value: `{"tag":"` + tag + `"}`})
+		events.Append(ctx, store.Event{Type: store.EventIngest, AlarmID: id, Tag: tag, Actor: "syslog"})
+	}
+	return alarms, events
+}
+
+func TestExportRestoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	alarms, events := seededStore(t)
+
+	var buf bytes.Buffer
+	stats, err := Export(ctx, &buf, alarms, events, Options{Events: true})
+	if err != nil || stats.Alarms != 3 || stats.Events != 3 {
+		t.Fatalf("unexpected export result %+v, %v", stats, err)
+	}
+
+	target := store.NewMemoryStore()
+	targetEvents := store.NewMemoryEventLog(0)
+	stats, err = Restore(ctx, &buf, target, targetEvents, RestoreOptions{Options: Options{Events: true}})
+	if err != nil || stats.Alarms != 3 || stats.Events != 3 {
+		t.Fatalf("unexpected restore result %+v, %v", stats, err)
+	}
+
+	for _, id := range []string{"a", "b", "c"} {
+		want, _ := alarms.Get(ctx, id)
+		got, err := target.Get(ctx, id)
+		if err != nil || got.Tag != want.Tag || got.Value != want.Value || !got.ReceivedAt.Equal(want.ReceivedAt) {
+			t.Fatalf("restored %s = %+v, %v; want %+v", id, got, err, want)
+		}
+	}
+	page, _ := targetEvents.Events(ctx, store.EventQuery{})
+	if len(page.Events) != 3 || !strings.Contains(page.Events[0].Message, "restored from backup") {
+		t.Fatalf("expected restored events to note their origin, got %+v", page.Events)
+	}
+	original, _ := events.Events(ctx, store.EventQuery{})
+	for i, e := range page.Events {
+		want := original.Events[i]
+		if e.AlarmID != want.AlarmID || e.OccurredAt == nil || !e.OccurredAt.Equal(want.Time) {
+			t.Fatalf("event %d: expected %s from %s in original order, got %+v", i, want.AlarmID, want.Time, e)
+		}
+	}
+}
+
+func TestExportWritesEventsOldestFirstAcrossPages(t *testing.T) {
+	ctx := context.Background()
+	events := store.NewMemoryEventLog(0)
+	total := pageSize*2 + 10
+	for i := 0; i < total; i++ {
+		events.Append(ctx, store.Event{Type: store.EventComment, AlarmID: "a", Message: strconv.Itoa(i)})
+	}
+
+	var buf bytes.Buffer
+	if _, err := Export(ctx, &buf, store.NewMemoryStore(), events, Options{Events: true}); err != nil {
+		t.Fatal(err)
+	}
+	target := store.NewMemoryEventLog(0)
+	if _, err := Restore(ctx, &buf, store.NewMemoryStore(), target, RestoreOptions{Options: Options{Events: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, _ := target.Events(ctx, store.EventQuery{})
+	if len(page.Events) != total {
+		t.Fatalf("expected %d restored events, got %d", total, len(page.Events))
+	}
+	for i, e := range page.Events {
+		if want := strconv.Itoa(total-1-i) + " ("; !strings.HasPrefix(e.Message, want) {
+			t.Fatalf("event %d: expected message %q..., got %q", i, want, e.Message)
+		}
+	}
+}
+
+func TestRestoreReversesEventsOfVersionOneBackups(t *testing.T) {
+	backup := `{"format":"logvault-backup","version":1,"events":true}
+{"type":"event","event":{"id":"2-0","time":"2026-01-02T03:04:06Z","type":"ack","alarm_id":"a"}}
+{"type":"event","event":{"id":"1-0","time":"2026-01-02T03:04:05Z","type":"ingest","alarm_id":"a"}}
+`
+	ctx := context.Background()
+	target := store.NewMemoryEventLog(0)
+	if _, err := Restore(ctx, strings.NewReader(backup), store.NewMemoryStore(), target, RestoreOptions{Options: Options{Events: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, _ := target.Events(ctx, store.EventQuery{})
+	if len(page.Events) != 2 || page.Events[0].Type != store.EventAck || page.Events[1].Type != store.EventIngest {
+		t.Fatalf("expected the ack to be restored after the ingest, got %+v", page.Events)
+	}
+}
+
+func TestExportFiltersByTag(t *testing.T) {
+	alarms, events := seededStore(t)
+
+	var buf bytes.Buffer
+	stats, err := Export(context.Background(), &buf, alarms, events, Options{Tags: []string{"insights"}, Events: true})
+	if err != nil || stats.Alarms != 1 || stats.Events != 1 {
+		t.Fatalf("expected one INSIGHTS alarm and event, got %+v, %v", stats, err)
+	}
+}
+
+func TestRestoreConflictModes(t *testing.T) {
+	ctx := context.Background()
+	alarms, events := seededStore(t)
+	var buf bytes.Buffer
+	if _, err := Export(ctx, &buf, alarms, events, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	target := store.NewMemoryStore()
+	target.Put(ctx, store.Record{ID: "a", Tag: "ALARM", Value: `{"local":true}`})
+
+	stats, err := Restore(ctx, bytes.NewReader(data), target, nil, RestoreOptions{Conflict: ConflictSkip})
+	if err != nil || stats.Alarms != 2 || stats.Skipped != 1 {
+		t.Fatalf("unexpected skip result %+v, %v", stats, err)
+	}
+	if rec, _ := target.Get(ctx, "a"); rec.Value != `{"local":true}` {
+		t.Fatal("expected skip to keep the existing alarm")
+	}
+
+	if _, err := Restore(ctx, bytes.NewReader(data), target, nil, RestoreOptions{Conflict: ConflictFail}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+
+	stats, err = Restore(ctx, bytes.NewReader(data), target, nil, RestoreOptions{Conflict: ConflictOverwrite})
+	if err != nil || stats.Overwritten != 3 {
+		t.Fatalf("unexpected overwrite result %+v, %v", stats, err)
+	}
+	if rec, _ := target.Get(ctx, "a"); rec.Value != `{"tag":"ALARM"}` {
+		t.Fatal("expected overwrite to replace the existing alarm")
+	}
+}
+
+func TestRestoreDryRunWritesNothing(t *testing.T) {
+	ctx := context.Background()
+	alarms, events := seededStore(t)
+	var buf bytes.Buffer
+	if _, err := Export(ctx, &buf, alarms, events, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	target := store.NewMemoryStore()
+	stats, err := Restore(ctx, &buf, target, nil, RestoreOptions{DryRun: true})
+	if err != nil || stats.Alarms != 3 {
+		t.Fatalf("unexpected dry-run result %+v, %v", stats, err)
+	}
+	if all, _ := target.List(ctx); len(all) != 0 {
+		t.Fatalf("expected dry run to write nothing, got %d alarms", len(all))
+	}
+}
+
+func TestRestoreRejectsNewerVersion(t *testing.T) {
+	backup := `{"format":"logvault-backup","version":99}` + "\n"
+	_, err := Restore(context.Background(), strings.NewReader(backup), store.NewMemoryStore(), nil, RestoreOptions{})
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("expected newer version to be rejected, got %v", err)
+	}
+}
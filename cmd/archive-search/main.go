@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"logvault/archive"
+)
+
+type fieldFlags map[string]string
+
+func (f fieldFlags) String() string {
+	parts := make([]string, 0, len(f))
+	for k, v := range f {
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f fieldFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	f[key] = val
+	return nil
+}
+
+func main() {
+	var dir, from, to string
+	var limit int
+	fields := fieldFlags{}
+
+	flag.StringVar(&dir, "dir", "./data/archive", "archive directory")
+	flag.StringVar(&from, "from", "", "earliest event time (RFC3339 or YYYY-MM-DD)")
+	flag.StringVar(&to, "to", "", "latest event time (RFC3339 or YYYY-MM-DD, inclusive)")
+	flag.Var(fields, "field", "match key=value on an entry or parsed alarm field (repeatable)")
+	flag.IntVar(&limit, "limit", 0, "stop after this many matches (0 = no limit)")
+	flag.Parse()
+
+	filter := archive.Filter{Fields: fields}
+	var err error
+	if filter.From, err = parseTime(from, false); err != nil {
+		log.Fatalf("invalid -from: %v", err)
+	}
+	if filter.To, err = parseTime(to, true); err != nil {
+		log.Fatalf("invalid -to: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	matched := 0
+	err = archive.Search(dir, filter, func(e archive.Entry) error {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+		matched++
+		if limit > 0 && matched >= limit {
+			return archive.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("failed to search archive: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "%d matching events\n", matched)
+}
+
+// parseTime accepts RFC3339 timestamps or plain dates. A plain date used as
+// an upper bound covers the whole day.
+func parseTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 or YYYY-MM-DD, got %q", value)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
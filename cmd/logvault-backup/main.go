@@ -0,0 +1,166 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"logvault/backup"
+	"logvault/config"
+	"logvault/redis"
+	"logvault/store"
+)
+
+const usage = `usage:
+  logvault-backup export [-config config.yaml] [-out backup.ndjson.gz] [-tag TAG,...] [-events]
+  logvault-backup restore [-config config.yaml] -in backup.ndjson.gz [-tag TAG,...] [-events] [-conflict skip|overwrite|fail] [-dry-run]
+
+Files ending in .gz are compressed. Use - for stdout or stdin.`
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	switch os.Args[1] {
+	case "export":
+		runExport(os.Args[2:])
+	case "restore":
+		runRestore(os.Args[2:])
+	default:
+		log.Fatal(usage)
+	}
+}
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "path to config.yaml")
+	out := fs.String("out", "-", "backup file to write")
+	tags := fs.String("tag", "", "comma-separated tags to export (default all)")
+	events := fs.Bool("events", false, "include the event history")
+	fs.Parse(args)
+
+	alarms, eventLog := openStore(*configPath)
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		file, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+		if err != nil {
+			log.Fatalf("failed to create backup file: %v", err)
+		}
+		defer file.Close()
+		w = file
+		if strings.HasSuffix(*out, ".gz") {
+			gz := gzip.NewWriter(file)
+			defer gz.Close()
+			w = gz
+		}
+	}
+
+	opts := backup.Options{Tags: splitTags(*tags), Events: *events}
+	stats, err := backup.Export(context.Background(), w, alarms, eventLog, opts)
+	if err != nil {
+		log.Fatalf("export failed: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d alarms and %d events\n", stats.Alarms, stats.Events)
+}
+
+func runRestore(args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "path to config.yaml")
+	in := fs.String("in", "", "backup file to read")
+	tags := fs.String("tag", "", "comma-separated tags to restore (default all)")
+	events := fs.Bool("events", false, "restore the event history as well")
+	conflict := fs.String("conflict", backup.ConflictSkip, "what to do with alarms that already exist: skip, overwrite or fail")
+	dryRun := fs.Bool("dry-run", false, "report what would be restored without writing")
+	fs.Parse(args)
+
+	if *in == "" {
+		log.Fatal("-in is required")
+	}
+
+	alarms, eventLog := openStore(*configPath)
+	opts := backup.RestoreOptions{
+		Options:  backup.Options{Tags: splitTags(*tags), Events: *events},
+		Conflict: *conflict,
+		DryRun:   *dryRun,
+	}
+
+	// With -conflict fail, check the whole backup first so nothing is
+	// written when any alarm already exists.
+	if opts.Conflict == backup.ConflictFail && !opts.DryRun && *in != "-" {
+		check := opts
+		check.DryRun = true
+		restore(*in, alarms, eventLog, check)
+	}
+
+	stats := restore(*in, alarms, eventLog, opts)
+	verb := "Restored"
+	if opts.DryRun {
+		verb = "Would restore"
+	}
+	fmt.Fprintf(os.Stderr, "%s %d alarms (%d overwritten, %d skipped) and %d events\n", verb, stats.Alarms, stats.Overwritten, stats.Skipped, stats.Events)
+}
+
+func restore(path string, alarms store.AlarmStore, events store.EventLog, opts backup.RestoreOptions) backup.Stats {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("failed to open backup file: %v", err)
+		}
+		defer file.Close()
+		r = file
+		if strings.HasSuffix(path, ".gz") {
+			gz, err := gzip.NewReader(file)
+			if err != nil {
+				log.Fatalf("failed to read backup file: %v", err)
+			}
+			defer gz.Close()
+			r = gz
+		}
+	}
+
+	stats, err := backup.Restore(context.Background(), r, alarms, events, opts)
+	if err != nil {
+		log.Fatalf("restore failed after %d alarms: %v", stats.Alarms, err)
+	}
+	return stats
+}
+
+// openStore connects to the Redis store configured in config.yaml, including
+// its namespace.
+func openStore(configPath string) (store.AlarmStore, store.EventLog) {
+	appConfig, err := config.LoadConfigFile(configPath)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	if backend := strings.ToLower(appConfig.Storage.Backend); backend != "redis" && backend != "" {
+		log.Fatalf("storage.backend %q cannot be backed up; only redis is persistent", appConfig.Storage.Backend)
+	}
+
+	rdb, err := redis.NewRedisClient(redis.OptionsFromConfig(appConfig))
+	if err != nil {
+		log.Fatalf("failed to connect to Redis: %v", err)
+	}
+	alarms := redis.NewAlarmStore(rdb)
+	if _, err := alarms.SyncIndex(context.Background()); err != nil {
+		log.Fatalf("failed to sync alarm index: %v", err)
+	}
+	return alarms, redis.NewEventLog(rdb, appConfig.Events.MaxLen)
+}
+
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/redis"
+)
+
+func main() {
+	var configPath string
+	var dryRun bool
+
+	flag.StringVar(&configPath, "config", "config.yaml", "path to config.yaml")
+	flag.BoolVar(&dryRun, "dry-run", false, "count alarms that need upgrading without rewriting them")
+	flag.Parse()
+
+	appConfig, err := config.LoadConfigFile(configPath)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	if backend := strings.ToLower(appConfig.Storage.Backend); backend != "redis" && backend != "" {
+		log.Fatalf("storage.backend %q has nothing to migrate", appConfig.Storage.Backend)
+	}
+
+	rdb, err := redis.NewRedisClient(redis.OptionsFromConfig(appConfig))
+	if err != nil {
+		log.Fatalf("failed to connect to Redis: %v", err)
+	}
+	alarms := redis.NewAlarmStore(rdb)
+	if _, err := alarms.SyncIndex(context.Background()); err != nil {
+		log.Fatalf("failed to sync alarm index: %v", err)
+	}
+
+	total, upgraded, err := alarm.Migrate(context.Background(), alarms, dryRun)
+	if err != nil {
+		log.Fatalf("migration failed after %d of %d alarms: %v", upgraded, total, err)
+	}
+
+	verb := "Upgraded"
+	if dryRun {
+		verb = "Would upgrade"
+	}
+	fmt.Printf("%s %d of %d alarms to schema version %d\n", verb, upgraded, total, alarm.SchemaVersion)
+}
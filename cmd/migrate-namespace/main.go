@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"logvault/config"
+	"logvault/redis"
+)
+
+func main() {
+	var configPath string
+	var from string
+	var to string
+	var dryRun bool
+
+	flag.StringVar(&configPath, "config", "config.yaml", "path to config.yaml")
+	flag.StringVar(&from, "from", "", "namespace to move keys from (empty for unprefixed alarm:* keys)")
+	flag.StringVar(&to, "to", "", "namespace to move keys to (defaults to redis.namespace from the config)")
+	flag.BoolVar(&dryRun, "dry-run", false, "show what would be moved without changing anything")
+	flag.Parse()
+
+	appConfig, err := config.LoadConfigFile(configPath)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	opts := redis.OptionsFromConfig(appConfig)
+	if to != "" {
+		opts.Namespace = to
+	}
+	if opts.Namespace == from {
+		log.Fatal("target namespace must differ from -from; set -to or redis.namespace")
+	}
+
+	rdb, err := redis.NewRedisClient(opts)
+	if err != nil {
+		log.Fatalf("failed to connect to Redis: %v", err)
+	}
+
+	result, err := rdb.MigrateNamespace(context.Background(), from, dryRun)
+	if err != nil {
+		log.Fatalf("migration failed after moving %d keys: %v", len(result.Moved), err)
+	}
+
+	verb := "Moved"
+	if dryRun {
+		verb = "Would move"
+	}
+	fmt.Printf("%s %d keys from namespace %q to %q\n", verb, len(result.Moved), from, opts.Namespace)
+	for _, key := range result.Conflicts {
+		fmt.Printf("Skipped %s: target key already exists\n", key)
+	}
+}
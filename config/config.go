@@ -2,6 +2,7 @@ package config
 
 import (
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -13,11 +14,44 @@ type Config struct {
 		Port       int      `mapstructure:"port"`
 		Protocol   string   `mapstructure:"protocol"`
 		AllowedIPs []string `mapstructure:"allowed_ips"`
+		SenderTags []struct {
+			Sources []string `mapstructure:"sources"`
+			Tags    []string `mapstructure:"tags"`
+		} `mapstructure:"sender_tags"`
 	} `mapstructure:"syslog"`
+	Storage struct {
+		Backend string `mapstructure:"backend"`
+	} `mapstructure:"storage"`
 	Redis struct {
-		Address  string `mapstructure:"address"`
-		Password string `mapstructure:"password"`
-		DB       int    `mapstructure:"db"`
+		Namespace string `mapstructure:"namespace"`
+		Address   string `mapstructure:"address"`
+		Username  string `mapstructure:"username"`
+		Password  string `mapstructure:"password"`
+		DB        int    `mapstructure:"db"`
+		Sentinel  struct {
+			MasterName string   `mapstructure:"master_name"`
+			Addresses  []string `mapstructure:"addresses"`
+			Username   string   `mapstructure:"username"`
+			Password   string   `mapstructure:"password"`
+		} `mapstructure:"sentinel"`
+		TLS struct {
+			Enabled            bool   `mapstructure:"enabled"`
+			CAFile             string `mapstructure:"ca_file"`
+			CertFile           string `mapstructure:"cert_file"`
+			KeyFile            string `mapstructure:"key_file"`
+			ServerName         string `mapstructure:"server_name"`
+			InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+		} `mapstructure:"tls"`
+		PoolSize     int           `mapstructure:"pool_size"`
+		MinIdleConns int           `mapstructure:"min_idle_conns"`
+		PoolTimeout  time.Duration `mapstructure:"pool_timeout"`
+		IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+		DialTimeout  time.Duration `mapstructure:"dial_timeout"`
+		ReadTimeout  time.Duration `mapstructure:"read_timeout"`
+		WriteTimeout time.Duration `mapstructure:"write_timeout"`
+		MaxRetries   int           `mapstructure:"max_retries"`
+
+		StartupTimeout time.Duration `mapstructure:"startup_timeout"`
 	} `mapstructure:"redis"`
 	Web struct {
 		Port       int    `mapstructure:"port"`
@@ -34,6 +68,8 @@ type Config struct {
 		KeyFile    string   `mapstructure:"key_file"`
 		AllowedIPs []string `mapstructure:"allowed_ips"`
 		CORSOrigin string   `mapstructure:"cors_origin"`
+
+		ReadOnlyCanAck bool `mapstructure:"readonly_can_ack"`
 	} `mapstructure:"web"`
 	API struct {
 		BearerToken string `mapstructure:"bearer_token"`
@@ -45,15 +81,114 @@ type Config struct {
 		BearerToken string `mapstructure:"bearer_token"`
 		TriggerTags string `mapstructure:"trigger_tags"`
 	} `mapstructure:"external_api"`
+	Events struct {
+		MaxLen int `mapstructure:"max_len"`
+	} `mapstructure:"events"`
+	Retention struct {
+		Interval time.Duration `mapstructure:"interval"`
+		Default  time.Duration `mapstructure:"default"`
+		Notify   bool          `mapstructure:"notify"`
+		Rules    []struct {
+			Tag      string        `mapstructure:"tag"`
+			Severity string        `mapstructure:"severity"`
+			MaxAge   time.Duration `mapstructure:"max_age"`
+		} `mapstructure:"rules"`
+	} `mapstructure:"retention"`
+	AutoResolve struct {
+		Interval time.Duration `mapstructure:"interval"`
+		Rules    []struct {
+			Tag   string        `mapstructure:"tag"`
+			After time.Duration `mapstructure:"after"`
+		} `mapstructure:"rules"`
+	} `mapstructure:"auto_resolve"`
+	Escalation struct {
+		Interval time.Duration `mapstructure:"interval"`
+		Lookback time.Duration `mapstructure:"lookback"`
+		Targets  []struct {
+			Name        string `mapstructure:"name"`
+			URL         string `mapstructure:"url"`
+			Method      string `mapstructure:"method"`
+			BearerToken string `mapstructure:"bearer_token"`
+		} `mapstructure:"targets"`
+		Policies []struct {
+			Name       string   `mapstructure:"name"`
+			Tags       []string `mapstructure:"tags"`
+			Severities []string `mapstructure:"severities"`
+			Levels     []struct {
+				After  time.Duration `mapstructure:"after"`
+				Target string        `mapstructure:"target"`
+			} `mapstructure:"levels"`
+		} `mapstructure:"policies"`
+	} `mapstructure:"escalation"`
+	Heartbeat struct {
+		CheckInterval time.Duration `mapstructure:"check_interval"`
+		Senders       []struct {
+			Name       string        `mapstructure:"name"`
+			Address    string        `mapstructure:"address"`
+			MaxSilence time.Duration `mapstructure:"max_silence"`
+		} `mapstructure:"senders"`
+	} `mapstructure:"heartbeat"`
+	Archive struct {
+		Enabled       bool          `mapstructure:"enabled"`
+		Dir           string        `mapstructure:"dir"`
+		MaxAge        time.Duration `mapstructure:"max_age"`
+		MaxSizeMB     int64         `mapstructure:"max_size_mb"`
+		FlushInterval time.Duration `mapstructure:"flush_interval"`
+	} `mapstructure:"archive"`
+	Trash struct {
+		Enabled       bool          `mapstructure:"enabled"`
+		Retention     time.Duration `mapstructure:"retention"`
+		PurgeInterval time.Duration `mapstructure:"purge_interval"`
+	} `mapstructure:"trash"`
+	Silences struct {
+		RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+		Retention       time.Duration `mapstructure:"retention"`
+	} `mapstructure:"silences"`
+	Maintenance struct {
+		Windows []struct {
+			Name     string        `mapstructure:"name"`
+			Schedule string        `mapstructure:"schedule"`
+			Duration time.Duration `mapstructure:"duration"`
+			Timezone string        `mapstructure:"timezone"`
+			Senders  []string      `mapstructure:"senders"`
+			Tags     []string      `mapstructure:"tags"`
+		} `mapstructure:"windows"`
+	} `mapstructure:"maintenance"`
+	Incidents struct {
+		Rules []struct {
+			Name   string        `mapstructure:"name"`
+			Tag    string        `mapstructure:"tag"`
+			Fields []string      `mapstructure:"fields"`
+			Window time.Duration `mapstructure:"window"`
+		} `mapstructure:"rules"`
+	} `mapstructure:"incidents"`
+	Lifecycle struct {
+		ReopenWindow time.Duration `mapstructure:"reopen_window"`
+	} `mapstructure:"lifecycle"`
+	Spool struct {
+		Enabled        bool          `mapstructure:"enabled"`
+		Dir            string        `mapstructure:"dir"`
+		ReplayInterval time.Duration `mapstructure:"replay_interval"`
+	} `mapstructure:"spool"`
 }
 
 // LoadConfig reads configuration from config.yaml
 func LoadConfig() (Config, error) {
+	return LoadConfigFile("")
+}
+
+// LoadConfigFile reads configuration from path, or from config.yaml in the
+// working directory when path is empty. Used by the command-line tools.
+func LoadConfigFile(path string) (Config, error) {
 	var appConfig Config
 
-	viper.SetConfigName("config")
+	if path != "" {
+		viper.SetConfigFile(path)
+	} else {
+		viper.SetConfigName("config")
+		viper.AddConfigPath(".")
+	}
 	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
@@ -63,16 +198,45 @@ func LoadConfig() (Config, error) {
 	viper.SetDefault("web.secret", "") // Default empty secret
 	viper.SetDefault("web.secret_hash", "")
 	viper.SetDefault("web.allowed_ips", []string{})
+	viper.SetDefault("web.readonly_can_ack", false)
 	viper.SetDefault("syslog.port", 514)
 	viper.SetDefault("syslog.host", "0.0.0.0")
 	viper.SetDefault("syslog.allowed_ips", []string{})
+	viper.SetDefault("storage.backend", "redis")
 	viper.SetDefault("redis.address", "127.0.0.1:6379")
+	viper.SetDefault("redis.dial_timeout", 5*time.Second)
+	viper.SetDefault("redis.read_timeout", 3*time.Second)
+	viper.SetDefault("redis.write_timeout", 3*time.Second)
+	viper.SetDefault("redis.startup_timeout", time.Minute)
 	viper.SetDefault("api.bearer_token", "") // Default empty bearer token
 	viper.SetDefault("external_api.enabled", false)
 	viper.SetDefault("external_api.url", "")
 	viper.SetDefault("external_api.method", "POST")
 	viper.SetDefault("external_api.bearer_token", "")
 	viper.SetDefault("external_api.trigger_tags", "ALARM")
+	viper.SetDefault("events.max_len", 100000)
+	viper.SetDefault("retention.interval", 5*time.Minute)
+	viper.SetDefault("retention.default", 0) // Keep alarms without a matching rule forever
+	viper.SetDefault("retention.notify", false)
+	viper.SetDefault("auto_resolve.interval", time.Minute)
+	viper.SetDefault("escalation.interval", 30*time.Second)
+	viper.SetDefault("escalation.lookback", 24*time.Hour)
+	viper.SetDefault("heartbeat.check_interval", 30*time.Second)
+	viper.SetDefault("archive.enabled", false)
+	viper.SetDefault("archive.dir", "./data/archive")
+	// Keep a year of archived messages for compliance retention
+	viper.SetDefault("archive.max_age", 365*24*time.Hour)
+	viper.SetDefault("archive.max_size_mb", 0) // No size limit
+	viper.SetDefault("archive.flush_interval", 5*time.Second)
+	viper.SetDefault("trash.enabled", true)
+	viper.SetDefault("trash.retention", 7*24*time.Hour)
+	viper.SetDefault("trash.purge_interval", time.Hour)
+	viper.SetDefault("silences.refresh_interval", time.Minute)
+	viper.SetDefault("silences.retention", 7*24*time.Hour)
+	viper.SetDefault("lifecycle.reopen_window", 24*time.Hour)
+	viper.SetDefault("spool.enabled", true)
+	viper.SetDefault("spool.dir", "./data/spool")
+	viper.SetDefault("spool.replay_interval", 10*time.Second)
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
@@ -0,0 +1,273 @@
+package escalation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/notifier"
+	"logvault/store"
+)
+
+// DefaultTarget names the external_api endpoint as an escalation target. It
+// is used for levels without a target.
+const DefaultTarget = "external_api"
+
+const sweepPageSize = 1000
+
+// Level is one step of a policy: after an alarm has been open this long
+// without being acknowledged, it is sent to target.
+type Level struct {
+	After  time.Duration
+	Target string
+}
+
+// Policy escalates open alarms with one of its tags and severities, or any
+// when empty, through its levels in order.
+type Policy struct {
+	Name       string
+	tags       []string
+	severities []string
+	Levels     []Level
+}
+
+// Applies reports whether the policy covers doc.
+func (p Policy) Applies(doc alarm.Document) bool {
+	return matches(p.tags, doc.Tag) && matches(p.severities, doc.Severity)
+}
+
+func matches(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Escalator notifies escalation targets about alarms that stay open, i.e.
+// unacknowledged, for longer than the delays of their policy's levels. An
+// alarm escalates one level at a time; acknowledging, resolving or closing
+// it cancels the remaining levels, and it starts over from the first level
+// when it is reopened.
+type Escalator struct {
+	alarms    store.AlarmStore
+	events    store.EventLog
+	appConfig config.Config
+	policies  []Policy
+	targets   map[string]notifier.Target
+	lookback  time.Duration
+
+	// tags are the tags of every policy when all policies name tags, so a
+	// sweep only reads those tags' index. It is nil when some policy
+	// applies to every tag and sweeps read every alarm in the lookback.
+	tags []string
+}
+
+// New builds an escalator from the escalation config block. Every policy
+// needs a name and levels with increasing positive delays and known
+// targets.
+func New(alarms store.AlarmStore, events store.EventLog, appConfig config.Config) (*Escalator, error) {
+	cfg := appConfig.Escalation
+	e := &Escalator{alarms: alarms, events: events, appConfig: appConfig, lookback: cfg.Lookback, targets: make(map[string]notifier.Target)}
+
+	for _, t := range cfg.Targets {
+		name := strings.TrimSpace(t.Name)
+		if name == "" || name == DefaultTarget || t.URL == "" {
+			return nil, fmt.Errorf("escalation target %q: a name other than %s and a url are required", t.Name, DefaultTarget)
+		}
+		e.targets[name] = notifier.Target{URL: t.URL, Method: t.Method, BearerToken: t.BearerToken}
+	}
+
+	for _, p := range cfg.Policies {
+		policy := Policy{Name: strings.TrimSpace(p.Name), tags: p.Tags, severities: p.Severities}
+		if policy.Name == "" {
+			return nil, fmt.Errorf("escalation policy: name is required")
+		}
+		if len(p.Levels) == 0 {
+			return nil, fmt.Errorf("escalation policy %q: at least one level is required", policy.Name)
+		}
+		var prev time.Duration
+		for i, l := range p.Levels {
+			target := strings.TrimSpace(l.Target)
+			if target == "" {
+				target = DefaultTarget
+			}
+			if _, ok := e.targets[target]; !ok && target != DefaultTarget {
+				return nil, fmt.Errorf("escalation policy %q: level %d has unknown target %q", policy.Name, i+1, target)
+			}
+			if l.After <= prev {
+				return nil, fmt.Errorf("escalation policy %q: level %d must come after the previous one", policy.Name, i+1)
+			}
+			prev = l.After
+			policy.Levels = append(policy.Levels, Level{After: l.After, Target: target})
+		}
+		e.policies = append(e.policies, policy)
+	}
+	e.tags = policyTags(e.policies)
+	return e, nil
+}
+
+// policyTags returns the distinct tags of policies, or nil if one of them
+// applies to every tag.
+func policyTags(policies []Policy) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, p := range policies {
+		if len(p.tags) == 0 {
+			return nil
+		}
+		for _, tag := range p.tags {
+			tag = strings.ToUpper(strings.TrimSpace(tag))
+			if tag != "" && !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// Run escalates alarms every interval. It returns immediately when no
+// policies are configured.
+func (e *Escalator) Run(interval time.Duration) {
+	if len(e.policies) == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		escalated, err := e.Sweep(context.Background(), time.Now())
+		if err != nil {
+			log.Printf("Escalation sweep failed: %v", err)
+		}
+		if escalated > 0 {
+			log.Printf("ESCALATION: Escalated %d alarms", escalated)
+		}
+	}
+}
+
+// Policy returns the first policy that applies to doc, or nil.
+func (e *Escalator) Policy(doc alarm.Document) *Policy {
+	for i := range e.policies {
+		if e.policies[i].Applies(doc) {
+			return &e.policies[i]
+		}
+	}
+	return nil
+}
+
+// Sweep escalates every open alarm received within the lookback whose next
+// level is due at now, and returns how many were escalated. Silenced alarms
+// and alarms raised during maintenance are not escalated. When every policy
+// names its tags, only alarms with those tags are read; otherwise every
+// alarm received within the lookback is read and decoded on each sweep.
+func (e *Escalator) Sweep(ctx context.Context, now time.Time) (int, error) {
+	if len(e.policies) == 0 {
+		return 0, nil
+	}
+	var since time.Time
+	if e.lookback > 0 {
+		since = now.Add(-e.lookback)
+	}
+
+	tags := e.tags
+	if tags == nil {
+		tags = []string{""}
+	}
+	escalated := 0
+	for _, tag := range tags {
+		n, err := e.sweepTag(ctx, tag, since, now)
+		escalated += n
+		if err != nil {
+			return escalated, err
+		}
+	}
+	return escalated, nil
+}
+
+// sweepTag escalates the due alarms with tag, or with any tag when it is
+// empty, received since since.
+func (e *Escalator) sweepTag(ctx context.Context, tag string, since, now time.Time) (int, error) {
+	q := store.Query{Tag: tag, Since: since, Limit: sweepPageSize}
+	escalated := 0
+	for {
+		page, err := e.alarms.Query(ctx, q)
+		if err != nil {
+			return escalated, err
+		}
+
+		for _, rec := range page.Records {
+			doc, _ := alarm.Decode(rec)
+			if doc.Status() != alarm.StatusOpen || doc.Suppressed() {
+				continue
+			}
+			policy := e.Policy(doc)
+			if policy == nil {
+				continue
+			}
+			level := doc.EscalationLevel()
+			if level >= len(policy.Levels) || now.Sub(doc.OpenedAt()) < policy.Levels[level].After {
+				continue
+			}
+
+			ok, err := e.escalate(ctx, doc, *policy, level+1, now)
+			if err != nil {
+				return escalated, err
+			}
+			if ok {
+				escalated++
+			}
+		}
+
+		if page.NextCursor == "" {
+			return escalated, nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+// escalate records the level on the alarm and then notifies its target.
+// alarm.Escalate only records the level while the alarm is open and the
+// level is its next one, atomically, so nothing is sent for an alarm that
+// was acknowledged, or escalated by another sweep, since it was read.
+func (e *Escalator) escalate(ctx context.Context, doc alarm.Document, policy Policy, level int, now time.Time) (bool, error) {
+	target := policy.Levels[level-1].Target
+	doc, ok, err := alarm.Escalate(ctx, e.alarms, e.events, doc.ID, alarm.Escalation{
+		Policy: policy.Name,
+		Level:  level,
+		Target: target,
+		At:     now,
+	})
+	if err != nil || !ok {
+		return false, err
+	}
+	log.Printf("ESCALATION: %s alarm %s escalated to %s (%s level %d)", doc.Tag, store.Key(doc.ID), target, policy.Name, level)
+
+	rec, err := doc.Record()
+	if err != nil {
+		return true, err
+	}
+	payload := map[string]string{"key": store.Key(doc.ID), "message": rec.Value, "status": "ESCALATED"}
+	if target == DefaultTarget {
+		go notifier.CallExternalAPI(e.appConfig, payload)
+		return true, nil
+	}
+	go func(t notifier.Target) {
+		if err := notifier.Send(t, payload); err != nil {
+			log.Printf("Failed to send escalation of key %s to %s: %v", store.Key(doc.ID), target, err)
+		}
+	}(e.targets[target])
+	return true, nil
+}
@@ -0,0 +1,177 @@
+package escalation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/store"
+)
+
+func newTestConfig() config.Config {
+	cfg := config.Config{}
+	cfg.Escalation.Targets = []struct {
+		Name        string `mapstructure:"name"`
+		URL         string `mapstructure:"url"`
+		Method      string `mapstructure:"method"`
+		BearerToken string `mapstructure:"bearer_token"`
+	}{
+		{Name: "pager", URL: "http://127.0.0.1:0/page"},
+	}
+	cfg.Escalation.Policies = []struct {
+		Name       string   `mapstructure:"name"`
+		Tags       []string `mapstructure:"tags"`
+		Severities []string `mapstructure:"severities"`
+		Levels     []struct {
+			After  time.Duration `mapstructure:"after"`
+			Target string        `mapstructure:"target"`
+		} `mapstructure:"levels"`
+	}{
+		{
+			Name:       "critical",
+			Severities: []string{"crit"},
+			Levels: []struct {
+				After  time.Duration `mapstructure:"after"`
+				Target string        `mapstructure:"target"`
+			}{
+				{After: 15 * time.Minute},
+				{After: 30 * time.Minute, Target: "pager"},
+			},
+		},
+	}
+	return cfg
+}
+
+func TestNewRejectsUnknownTargets(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Escalation.Policies[0].Levels[1].Target = "sms"
+	if _, err := New(nil, nil, cfg); err == nil {
+		t.Fatal("expected a level with an unknown target to be rejected")
+	}
+
+	cfg = newTestConfig()
+	cfg.Escalation.Policies[0].Levels[1].After = 10 * time.Minute
+	if _, err := New(nil, nil, cfg); err == nil {
+		t.Fatal("expected levels out of order to be rejected")
+	}
+}
+
+func TestSweepEscalatesOneLevelAtATime(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+
+	put := func(id, severity string, age time.Duration, status string) {
+		doc := alarm.New(id, "ALARM", now.Add(-age))
+		doc.Severity = severity
+		doc.State.Status = status
+		rec, _ := doc.Record()
+		alarms.Put(ctx, rec)
+	}
+	put("stale", "crit", 40*time.Minute, alarm.StatusOpen)
+	put("recent", "crit", 5*time.Minute, alarm.StatusOpen)
+	put("acked", "crit", 40*time.Minute, alarm.StatusAcknowledged)
+	put("minor", "info", 40*time.Minute, alarm.StatusOpen)
+
+	e, err := New(alarms, events, newTestConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	level := func(id string) int {
+		rec, _ := alarms.Get(ctx, id)
+		doc, _ := alarm.Decode(rec)
+		return doc.EscalationLevel()
+	}
+
+	for i, want := range []int{1, 1, 0} {
+		escalated, err := e.Sweep(ctx, now)
+		if err != nil || escalated != want {
+			t.Fatalf("sweep %d: expected %d alarms escalated, got %d, %v", i+1, want, escalated, err)
+		}
+	}
+	if level("stale") != 2 || level("recent") != 0 || level("acked") != 0 || level("minor") != 0 {
+		t.Fatalf("expected only the stale critical alarm to reach level 2, got %d %d %d %d", level("stale"), level("recent"), level("acked"), level("minor"))
+	}
+
+	page, _ := events.Events(ctx, store.EventQuery{AlarmID: "stale"})
+	if len(page.Events) != 2 || page.Events[0].Type != store.EventEscalate {
+		t.Fatalf("expected two escalate events, got %+v", page.Events)
+	}
+}
+
+func TestAckCancelsEscalation(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	doc := alarm.New("stale", "ALARM", now.Add(-20*time.Minute))
+	doc.Severity = "crit"
+	rec, _ := doc.Record()
+	alarms.Put(ctx, rec)
+
+	e, err := New(alarms, events, newTestConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if escalated, _ := e.Sweep(ctx, now); escalated != 1 {
+		t.Fatalf("expected the alarm to escalate, got %d", escalated)
+	}
+
+	acked, err := alarm.Change(ctx, alarms, events, "stale", alarm.ActionAck, "alice", "")
+	if err != nil {
+		t.Fatalf("failed to ack alarm: %v", err)
+	}
+	last := acked.Escalations[len(acked.Escalations)-1]
+	if !last.Cancelled || last.By != "alice" {
+		t.Fatalf("expected ack to cancel escalation, got %+v", acked.Escalations)
+	}
+	if escalated, _ := e.Sweep(ctx, now.Add(time.Hour)); escalated != 0 {
+		t.Fatalf("expected no escalation of an acknowledged alarm, got %d", escalated)
+	}
+
+	if _, err := alarm.Change(ctx, alarms, events, "stale", alarm.ActionUnack, "alice", ""); err != nil {
+		t.Fatalf("failed to unack alarm: %v", err)
+	}
+	if escalated, _ := e.Sweep(ctx, time.Now().Add(16*time.Minute)); escalated != 1 {
+		t.Fatalf("expected an unacknowledged alarm to escalate again from the first level, got %d", escalated)
+	}
+}
+
+// queryRecorder records the tags alarms are queried by.
+type queryRecorder struct {
+	*store.MemoryStore
+	tags []string
+}
+
+func (s *queryRecorder) Query(ctx context.Context, q store.Query) (store.Page, error) {
+	s.tags = append(s.tags, q.Tag)
+	return s.MemoryStore.Query(ctx, q)
+}
+
+func TestSweepOnlyReadsPolicyTags(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	alarms := &queryRecorder{MemoryStore: store.NewMemoryStore()}
+	for _, tag := range []string{"ALARM", "INSIGHTS"} {
+		doc := alarm.New(tag, tag, now.Add(-20*time.Minute))
+		doc.Severity = "crit"
+		rec, _ := doc.Record()
+		alarms.Put(ctx, rec)
+	}
+
+	cfg := newTestConfig()
+	cfg.Escalation.Policies[0].Tags = []string{"alarm", "ALARM"}
+	e, err := New(alarms, store.NewMemoryEventLog(0), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if escalated, err := e.Sweep(ctx, now); err != nil || escalated != 1 {
+		t.Fatalf("expected the ALARM alarm to escalate, got %d, %v", escalated, err)
+	}
+	if len(alarms.tags) != 1 || alarms.tags[0] != "ALARM" {
+		t.Fatalf("expected a single query by the policy tag, got %q", alarms.tags)
+	}
+}
@@ -0,0 +1,411 @@
+package incident
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"logvault/alarm"
+	"logvault/store"
+)
+
+// Rule bundles alarms with its tag, or any tag when empty, that share the
+// values of its fields. An alarm joins the active incident of its group if
+// that incident saw an alarm within the window; otherwise it starts a new
+// one. Fields name keys of the alarm's fields, or "sender" and "severity".
+type Rule struct {
+	Name   string
+	Tag    string
+	Fields []string
+	Window time.Duration
+}
+
+// values returns the rule's field values for doc, or false if the rule does
+// not apply to it or one of the values is empty.
+func (r Rule) values(doc alarm.Document) (map[string]string, bool) {
+	if r.Tag != "" && !strings.EqualFold(r.Tag, doc.Tag) {
+		return nil, false
+	}
+	values := make(map[string]string, len(r.Fields))
+	for _, field := range r.Fields {
+		var v string
+		switch strings.ToLower(field) {
+		case "sender":
+			v = doc.Source.IP
+			if v == "" {
+				v = strings.ToLower(doc.Source.Hostname)
+			}
+		case "severity":
+			v = doc.Severity
+		default:
+			v = doc.Fields[field]
+		}
+		if v = strings.TrimSpace(v); v == "" || strings.EqualFold(v, "NULL") {
+			return nil, false
+		}
+		values[field] = v
+	}
+	return values, true
+}
+
+// group identifies the incidents of a rule that share values.
+func (r Rule) group(values map[string]string) string {
+	parts := []string{r.Name}
+	for _, field := range r.Fields {
+		parts = append(parts, field, values[field])
+	}
+	return alarm.Fingerprint(parts...)
+}
+
+// maxMembers caps how many alarm IDs an incident lists, so an outbreak does
+// not grow the stored incident without bound.
+const maxMembers = 1000
+
+// maxUpdateAttempts bounds how often an incident update retries when another
+// writer, such as another instance sharing the store, changed it first.
+const maxUpdateAttempts = 10
+
+// Incident is a bundle of related alarms with a lifecycle of its own.
+// Count is how many alarms joined it, recurrences included; Members are
+// their IDs, oldest first, up to maxMembers. Truncated is set once an alarm
+// joined that is not listed; every alarm still names the incident it joined.
+// Notified is set once the incident was notified for its first alarm that
+// was not silenced.
+type Incident struct {
+	ID        string            `json:"id"`
+	Rule      string            `json:"rule"`
+	Tag       string            `json:"tag"`
+	Group     string            `json:"group"`
+	Values    map[string]string `json:"values"`
+	FirstSeen time.Time         `json:"first_seen"`
+	LastSeen  time.Time         `json:"last_seen"`
+	Count     int               `json:"count"`
+	Members   []string          `json:"members"`
+	Truncated bool              `json:"truncated,omitempty"`
+	State     alarm.State       `json:"state"`
+	Notified  bool              `json:"notified,omitempty"`
+}
+
+// Status returns the lifecycle status, treating an unset status as open.
+func (inc Incident) Status() string {
+	return inc.State.Current()
+}
+
+// Key returns the key incidents are notified under.
+func (inc Incident) Key() string {
+	return "incident:" + inc.ID
+}
+
+// record encodes the incident for storage. Incidents are ordered by their
+// last alarm, so the newest of a group is found by a time-bounded query.
+func (inc Incident) record() (store.Record, error) {
+	value, err := json.Marshal(inc)
+	if err != nil {
+		return store.Record{}, err
+	}
+	return store.Record{ID: inc.ID, Tag: inc.Tag, ReceivedAt: inc.LastSeen, Value: string(value)}, nil
+}
+
+func decode(rec store.Record) (Incident, error) {
+	var inc Incident
+	if err := json.Unmarshal([]byte(rec.Value), &inc); err != nil {
+		return inc, fmt.Errorf("decode incident %s: %w", rec.ID, err)
+	}
+	return inc, nil
+}
+
+// Grouper files alarms into incidents according to its rules. A nil Grouper
+// groups nothing. Incidents are updated with compare-and-swap, so alarms
+// joining an incident and actions on it do not overwrite each other, also
+// across instances sharing the store.
+type Grouper struct {
+	alarms    store.AlarmStore
+	incidents store.AlarmStore
+	events    store.EventLog
+	rules     []Rule
+}
+
+// New validates the rules, as configured in incidents.rules. Every rule
+// needs a name, at least one field and a positive window.
+func New(alarms, incidents store.AlarmStore, events store.EventLog, rules []Rule) (*Grouper, error) {
+	g := &Grouper{alarms: alarms, incidents: incidents, events: events}
+	for _, r := range rules {
+		rule := Rule{Name: strings.TrimSpace(r.Name), Tag: strings.TrimSpace(r.Tag), Window: r.Window}
+		for _, field := range r.Fields {
+			if field = strings.TrimSpace(field); field != "" {
+				rule.Fields = append(rule.Fields, field)
+			}
+		}
+		switch {
+		case rule.Name == "":
+			return nil, fmt.Errorf("incident rule: name is required")
+		case len(rule.Fields) == 0:
+			return nil, fmt.Errorf("incident rule %q: at least one field is required", rule.Name)
+		case rule.Window <= 0:
+			return nil, fmt.Errorf("incident rule %q: window must be positive", rule.Name)
+		}
+		g.rules = append(g.rules, rule)
+	}
+	return g, nil
+}
+
+// Group files doc, an alarm that is already stored, into an incident under
+// the first rule that applies to it, and records the incident on the stored
+// alarm and in doc. It returns the incident, or nil if no rule applies.
+//
+// The alarms of an incident are notified as the incident, once: notify says
+// whether doc would be notified on its own, and Group reports true for the
+// first such alarm of the incident, which the caller notifies as the
+// incident, and false for the others. Silenced alarms therefore neither
+// notify the incident nor keep it from being notified by a later alarm.
+func (g *Grouper) Group(ctx context.Context, doc *alarm.Document, notify bool) (*Incident, bool, error) {
+	if g == nil {
+		return nil, false, nil
+	}
+	rule, values, ok := g.rule(*doc)
+	if !ok {
+		return nil, false, nil
+	}
+
+	inc, first, err := g.join(ctx, rule, values, *doc, notify)
+	if err != nil {
+		return nil, false, err
+	}
+
+	stored, err := alarm.SetIncident(ctx, g.alarms, doc.ID, inc.ID)
+	if err != nil {
+		return &inc, first, err
+	}
+	*doc = stored
+	return &inc, first, nil
+}
+
+// rule returns the first rule that applies to doc and its values.
+func (g *Grouper) rule(doc alarm.Document) (Rule, map[string]string, bool) {
+	for _, rule := range g.rules {
+		if values, ok := rule.values(doc); ok {
+			return rule, values, true
+		}
+	}
+	return Rule{}, nil, false
+}
+
+// join adds doc to the active incident of its group, or a new one, and
+// reports whether the incident is to be notified for doc. An incident that
+// changed since it was read is read again and doc added to it once more.
+func (g *Grouper) join(ctx context.Context, rule Rule, values map[string]string, doc alarm.Document, notify bool) (Incident, bool, error) {
+	group := rule.group(values)
+	for attempt := 1; ; attempt++ {
+		page, err := g.incidents.Query(ctx, store.Query{
+			Fields: map[string]string{"group": group},
+			Since:  doc.ReceivedAt.Add(-rule.Window),
+			Limit:  1,
+		})
+		if err != nil {
+			return Incident{}, false, err
+		}
+
+		if len(page.Records) > 0 {
+			if prev, err := decode(page.Records[0]); err == nil && prev.State.Active() {
+				first := prev.add(doc, notify)
+				rec, err := prev.record()
+				if err != nil {
+					return Incident{}, false, err
+				}
+				err = g.incidents.Swap(ctx, page.Records[0], rec)
+				if (errors.Is(err, store.ErrConflict) || errors.Is(err, store.ErrNotFound)) && attempt < maxUpdateAttempts {
+					continue
+				}
+				return prev, first, err
+			}
+		}
+
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return Incident{}, false, err
+		}
+		inc := Incident{
+			ID:        hex.EncodeToString(id),
+			Rule:      rule.Name,
+			Tag:       doc.Tag,
+			Group:     group,
+			Values:    values,
+			FirstSeen: doc.ReceivedAt,
+			State:     alarm.State{Status: alarm.StatusOpen},
+		}
+		first := inc.add(doc, notify)
+		rec, err := inc.record()
+		if err != nil {
+			return Incident{}, false, err
+		}
+		return inc, first, g.incidents.Put(ctx, rec)
+	}
+}
+
+// add counts doc as an alarm of the incident and reports whether the
+// incident is to be notified for it.
+func (inc *Incident) add(doc alarm.Document, notify bool) bool {
+	inc.Count++
+	if doc.ReceivedAt.After(inc.LastSeen) {
+		inc.LastSeen = doc.ReceivedAt
+	}
+	if !contains(inc.Members, doc.ID) {
+		if len(inc.Members) < maxMembers {
+			inc.Members = append(inc.Members, doc.ID)
+		} else {
+			inc.Truncated = true
+		}
+	}
+	first := notify && !inc.Notified
+	if first {
+		inc.Notified = true
+	}
+	return first
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the incident id, or store.ErrNotFound.
+func (g *Grouper) Get(ctx context.Context, id string) (Incident, error) {
+	rec, err := g.incidents.Get(ctx, id)
+	if err != nil {
+		return Incident{}, err
+	}
+	return decode(rec)
+}
+
+// Query returns one page of incidents matching q, most recently active
+// first, and the cursor of the next page.
+func (g *Grouper) Query(ctx context.Context, q store.Query) ([]Incident, string, error) {
+	page, err := g.incidents.Query(ctx, q)
+	if err != nil {
+		return nil, "", err
+	}
+	list := make([]Incident, 0, len(page.Records))
+	for _, rec := range page.Records {
+		inc, err := decode(rec)
+		if err != nil {
+			log.Printf("Skipping invalid incident: %v", err)
+			continue
+		}
+		list = append(list, inc)
+	}
+	return list, page.NextCursor, nil
+}
+
+// Members returns the listed alarms of the incident that still exist,
+// oldest first.
+func (g *Grouper) Members(ctx context.Context, inc Incident) ([]alarm.Document, error) {
+	docs := make([]alarm.Document, 0, len(inc.Members))
+	for _, id := range inc.Members {
+		rec, err := g.alarms.Get(ctx, id)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		doc, _ := alarm.Decode(rec)
+		docs = append(docs, doc)
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].ReceivedAt.Before(docs[j].ReceivedAt)
+	})
+	return docs, nil
+}
+
+// memberIDs returns the IDs of every alarm in the incident. For a truncated
+// incident, the alarms that name it are looked up among those received
+// since it started.
+func (g *Grouper) memberIDs(ctx context.Context, inc Incident) ([]string, error) {
+	if !inc.Truncated {
+		return inc.Members, nil
+	}
+	var ids []string
+	q := store.Query{Fields: map[string]string{"incident": inc.ID}, Since: inc.FirstSeen, Limit: 1000}
+	for {
+		page, err := g.alarms.Query(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range page.Records {
+			ids = append(ids, rec.ID)
+		}
+		if page.NextCursor == "" {
+			return ids, nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+// Change applies an action to the incident and then to each of its alarms
+// the action applies to. Alarms already in the target status, or deleted,
+// are skipped. It returns the updated incident and how many alarms changed,
+// or store.ErrNotFound or alarm.ErrInvalidTransition for the incident.
+func (g *Grouper) Change(ctx context.Context, id string, a alarm.Action, by, comment string) (Incident, int, error) {
+	inc, err := g.apply(ctx, id, a, by, comment)
+	if err != nil {
+		return inc, 0, err
+	}
+	members, err := g.memberIDs(ctx, inc)
+	if err != nil {
+		return inc, 0, err
+	}
+
+	message := comment
+	if message == "" {
+		message = "Via incident " + inc.ID
+	}
+	changed := 0
+	for _, member := range members {
+		_, err := alarm.Change(ctx, g.alarms, g.events, member, a, by, message)
+		switch {
+		case err == nil:
+			changed++
+		case errors.Is(err, store.ErrNotFound), errors.Is(err, alarm.ErrInvalidTransition):
+		default:
+			log.Printf("Failed to %s key %s of incident %s: %v", a.Name, store.Key(member), inc.ID, err)
+		}
+	}
+	return inc, changed, nil
+}
+
+// apply performs the action on the stored incident, retrying when it was
+// changed between its read and its write.
+func (g *Grouper) apply(ctx context.Context, id string, a alarm.Action, by, comment string) (Incident, error) {
+	for attempt := 1; ; attempt++ {
+		rec, err := g.incidents.Get(ctx, id)
+		if err != nil {
+			return Incident{}, err
+		}
+		inc, err := decode(rec)
+		if err != nil {
+			return inc, err
+		}
+		if err := inc.State.Apply(a, by, comment, time.Now()); err != nil {
+			return inc, err
+		}
+		updated, err := inc.record()
+		if err != nil {
+			return inc, err
+		}
+		err = g.incidents.Swap(ctx, rec, updated)
+		if !errors.Is(err, store.ErrConflict) || attempt == maxUpdateAttempts {
+			return inc, err
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package incident
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"logvault/alarm"
+	"logvault/store"
+)
+
+func newAlarm(id, rule string, at time.Time) alarm.Document {
+	doc := alarm.New(id, "INSIGHTS", at)
+	doc.Fields = map[string]string{"RuleName": rule}
+	return doc
+}
+
+func save(t *testing.T, alarms store.AlarmStore, doc alarm.Document) {
+	t.Helper()
+	rec, err := doc.Record()
+	if err != nil {
+		t.Fatalf("failed to encode alarm: %v", err)
+	}
+	if err := alarms.Put(context.Background(), rec); err != nil {
+		t.Fatalf("failed to store alarm: %v", err)
+	}
+}
+
+func TestNewRejectsIncompleteRules(t *testing.T) {
+	for _, rule := range []Rule{
+		{Fields: []string{"RuleName"}, Window: time.Minute},
+		{Name: "outbreak", Window: time.Minute},
+		{Name: "outbreak", Fields: []string{"RuleName"}},
+	} {
+		if _, err := New(nil, nil, nil, []Rule{rule}); err == nil {
+			t.Fatalf("expected rule %+v to be rejected", rule)
+		}
+	}
+}
+
+func TestGroupBundlesAlarmsWithinWindow(t *testing.T) {
+	ctx := context.Background()
+	alarms := store.NewMemoryStore()
+	g, err := New(alarms, store.NewMemoryStore(), store.NewMemoryEventLog(0), []Rule{
+		{Name: "outbreak", Tag: "insights", Fields: []string{"RuleName"}, Window: 10 * time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+
+	first := newAlarm("a1", "ransomware", now)
+	save(t, alarms, first)
+	inc, notify, err := g.Group(ctx, &first, true)
+	if err != nil || inc == nil || !notify || first.Incident != inc.ID {
+		t.Fatalf("expected the first alarm to open an incident, got %+v, %v, %v", inc, notify, err)
+	}
+	if rec, _ := alarms.Get(ctx, "a1"); !strings.Contains(rec.Value, inc.ID) {
+		t.Fatalf("expected the stored alarm to record incident %s, got %s", inc.ID, rec.Value)
+	}
+
+	second := newAlarm("a2", "ransomware", now.Add(5*time.Minute))
+	save(t, alarms, second)
+	if joined, notify, _ := g.Group(ctx, &second, true); notify || joined.ID != inc.ID || joined.Count != 2 {
+		t.Fatalf("expected the second alarm to join incident %s, got %+v", inc.ID, joined)
+	}
+
+	other := newAlarm("a3", "phishing", now.Add(5*time.Minute))
+	save(t, alarms, other)
+	if separate, notify, _ := g.Group(ctx, &other, true); !notify || separate.ID == inc.ID {
+		t.Fatalf("expected an alarm with another rule name to open its own incident, got %+v", separate)
+	}
+
+	late := newAlarm("a4", "ransomware", now.Add(20*time.Minute))
+	save(t, alarms, late)
+	if next, notify, _ := g.Group(ctx, &late, true); !notify || next.ID == inc.ID {
+		t.Fatalf("expected an alarm after the window to open a new incident, got %+v", next)
+	}
+
+	ungrouped := newAlarm("a5", "", now)
+	save(t, alarms, ungrouped)
+	if none, _, _ := g.Group(ctx, &ungrouped, true); none != nil || ungrouped.Incident != "" {
+		t.Fatalf("expected an alarm without the grouping field not to be grouped, got %+v", none)
+	}
+}
+
+func TestGroupNotifiesFirstUnsilencedAlarm(t *testing.T) {
+	ctx := context.Background()
+	alarms := store.NewMemoryStore()
+	g, err := New(alarms, store.NewMemoryStore(), store.NewMemoryEventLog(0), []Rule{
+		{Name: "outbreak", Fields: []string{"RuleName"}, Window: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	for i, want := range []bool{false, true, false} {
+		doc := newAlarm(fmt.Sprintf("a%d", i), "ransomware", now.Add(time.Duration(i)*time.Second))
+		save(t, alarms, doc)
+		// The first alarm is silenced, so it is not to be notified.
+		if _, notify, err := g.Group(ctx, &doc, i > 0); err != nil || notify != want {
+			t.Fatalf("alarm %d: expected notify %v, got %v, %v", i, want, notify, err)
+		}
+	}
+
+	missing := newAlarm("gone", "ransomware", now)
+	if _, _, err := g.Group(ctx, &missing, true); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected grouping an alarm that is not stored to fail, got %v", err)
+	}
+}
+
+func TestChangeCascadesToMembers(t *testing.T) {
+	ctx := context.Background()
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	g, err := New(alarms, store.NewMemoryStore(), events, []Rule{
+		{Name: "outbreak", Fields: []string{"RuleName"}, Window: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	var inc *Incident
+	for i, id := range []string{"a1", "a2", "a3"} {
+		doc := newAlarm(id, "ransomware", now.Add(time.Duration(i)*time.Second))
+		save(t, alarms, doc)
+		inc, _, _ = g.Group(ctx, &doc, true)
+	}
+	if _, err := alarm.Change(ctx, alarms, events, "a3", alarm.ActionResolve, "admin", ""); err != nil {
+		t.Fatalf("failed to resolve alarm: %v", err)
+	}
+
+	resolved, changed, err := g.Change(ctx, inc.ID, alarm.ActionResolve, "admin", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved.Status() != alarm.StatusResolved || changed != 2 {
+		t.Fatalf("expected the incident and two open alarms to be resolved, got %s and %d", resolved.Status(), changed)
+	}
+	docs, _ := g.Members(ctx, resolved)
+	for _, doc := range docs {
+		if doc.Status() != alarm.StatusResolved {
+			t.Fatalf("expected alarm %s to be resolved, got %s", doc.ID, doc.Status())
+		}
+	}
+
+	if _, _, err := g.Change(ctx, inc.ID, alarm.ActionAck, "admin", ""); err == nil {
+		t.Fatal("expected ack of a resolved incident to fail")
+	}
+	doc := newAlarm("a4", "ransomware", now.Add(time.Minute))
+	save(t, alarms, doc)
+	if next, notify, _ := g.Group(ctx, &doc, true); !notify || next.ID == inc.ID {
+		t.Fatalf("expected an alarm after resolution to open a new incident, got %+v", next)
+	}
+}
+
+// racingStore runs race once, before the first Swap, as another instance
+// updating the same incident between its read and write would.
+type racingStore struct {
+	*store.MemoryStore
+	race func()
+}
+
+func (s *racingStore) Swap(ctx context.Context, old, rec store.Record) error {
+	if race := s.race; race != nil {
+		s.race = nil
+		race()
+	}
+	return s.MemoryStore.Swap(ctx, old, rec)
+}
+
+func TestGroupKeepsConcurrentJoins(t *testing.T) {
+	ctx := context.Background()
+	alarms := store.NewMemoryStore()
+	incidents := &racingStore{MemoryStore: store.NewMemoryStore()}
+	rules := []Rule{{Name: "outbreak", Fields: []string{"RuleName"}, Window: time.Hour}}
+	g, _ := New(alarms, incidents, store.NewMemoryEventLog(0), rules)
+	other, _ := New(alarms, incidents.MemoryStore, store.NewMemoryEventLog(0), rules)
+
+	now := time.Now()
+	first := newAlarm("a1", "ransomware", now)
+	save(t, alarms, first)
+	inc, _, _ := g.Group(ctx, &first, true)
+
+	incidents.race = func() {
+		concurrent := newAlarm("a2", "ransomware", now.Add(time.Second))
+		save(t, alarms, concurrent)
+		if _, _, err := other.Group(ctx, &concurrent, true); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	third := newAlarm("a3", "ransomware", now.Add(2*time.Second))
+	save(t, alarms, third)
+	if _, _, err := g.Group(ctx, &third, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, _ := g.Get(ctx, inc.ID)
+	if got.Count != 3 || len(got.Members) != 3 {
+		t.Fatalf("expected all three alarms in the incident, got count %d and members %v", got.Count, got.Members)
+	}
+}
+
+func TestGroupCapsMembersAndChangeReachesUnlisted(t *testing.T) {
+	ctx := context.Background()
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	g, _ := New(alarms, store.NewMemoryStore(), events, []Rule{
+		{Name: "outbreak", Fields: []string{"RuleName"}, Window: time.Hour},
+	})
+
+	now := time.Now()
+	var inc *Incident
+	for i := 0; i <= maxMembers; i++ {
+		doc := newAlarm(fmt.Sprintf("a%d", i), "ransomware", now.Add(time.Duration(i)*time.Millisecond))
+		save(t, alarms, doc)
+		inc, _, _ = g.Group(ctx, &doc, true)
+	}
+	if len(inc.Members) != maxMembers || !inc.Truncated || inc.Count != maxMembers+1 {
+		t.Fatalf("expected %d listed members of %d, got %d listed, truncated %v, count %d", maxMembers, maxMembers+1, len(inc.Members), inc.Truncated, inc.Count)
+	}
+
+	if _, changed, err := g.Change(ctx, inc.ID, alarm.ActionAck, "admin", ""); err != nil || changed != maxMembers+1 {
+		t.Fatalf("expected every alarm to be acknowledged, got %d, %v", changed, err)
+	}
+}
@@ -0,0 +1,24 @@
+package incident
+
+import (
+	"encoding/json"
+	"log"
+
+	"logvault/config"
+	"logvault/notifier"
+)
+
+// StatusNotified is the status incidents are notified with for their first
+// alarm.
+const StatusNotified = "INCIDENT"
+
+// Notify sends inc to the external API under its key with the given status,
+// the incident itself being the message.
+func Notify(appConfig config.Config, inc Incident, status string) {
+	value, err := json.Marshal(inc)
+	if err != nil {
+		log.Printf("Failed to marshal incident %s: %v", inc.ID, err)
+		return
+	}
+	go notifier.CallExternalAPI(appConfig, map[string]string{"key": inc.Key(), "message": string(value), "status": status})
+}
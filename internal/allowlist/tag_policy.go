@@ -0,0 +1,86 @@
+package allowlist
+
+import (
+	"net"
+	"strings"
+)
+
+// TagRule binds a set of source IPs/CIDRs to the syslog tags they may emit.
+// A tag of "*" allows every tag.
+type TagRule struct {
+	Sources []string
+	Tags    []string
+}
+
+type tagRule struct {
+	sources *IPAllowlist
+	tags    map[string]struct{}
+	anyTag  bool
+}
+
+// TagPolicy decides which tags a sender is allowed to emit. Once any rule is
+// configured, a sender may only emit tags granted by a rule matching its IP.
+type TagPolicy struct {
+	rules   []tagRule
+	enabled bool
+}
+
+func NewTagPolicy(rules []TagRule) (*TagPolicy, error) {
+	policy := &TagPolicy{rules: make([]tagRule, 0, len(rules))}
+
+	for _, rule := range rules {
+		sources, err := New(rule.Sources)
+		if err != nil {
+			return nil, err
+		}
+		if !sources.Enabled() {
+			continue
+		}
+
+		compiled := tagRule{sources: sources, tags: make(map[string]struct{})}
+		for _, tag := range rule.Tags {
+			tag = strings.ToUpper(strings.TrimSpace(tag))
+			if tag == "" {
+				continue
+			}
+			if tag == "*" {
+				compiled.anyTag = true
+				continue
+			}
+			compiled.tags[tag] = struct{}{}
+		}
+
+		policy.rules = append(policy.rules, compiled)
+		policy.enabled = true
+	}
+
+	return policy, nil
+}
+
+func (p *TagPolicy) Allows(ip net.IP, tag string) bool {
+	if !p.enabled {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+
+	tag = strings.ToUpper(strings.TrimSpace(tag))
+	for _, rule := range p.rules {
+		if !rule.sources.Allows(ip) {
+			continue
+		}
+		if rule.anyTag {
+			return true
+		}
+		if _, ok := rule.tags[tag]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *TagPolicy) Enabled() bool {
+	return p.enabled
+}
@@ -0,0 +1,55 @@
+package allowlist
+
+import (
+	"net"
+	"testing"
+)
+
+func TestTagPolicyAllowsAllWhenDisabled(t *testing.T) {
+	policy, err := NewTagPolicy(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !policy.Allows(net.ParseIP("203.0.113.50"), "CLEAR") {
+		t.Fatal("expected disabled policy to allow every tag")
+	}
+}
+
+func TestTagPolicyAllowsBoundTag(t *testing.T) {
+	policy, err := NewTagPolicy([]TagRule{
+		{Sources: []string{"10.10.0.0/16"}, Tags: []string{"insights"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !policy.Allows(net.ParseIP("10.10.1.1"), "INSIGHTS") {
+		t.Fatal("expected bound tag to be allowed")
+	}
+	if policy.Allows(net.ParseIP("10.10.1.1"), "CLEAR") {
+		t.Fatal("expected unbound tag to be denied")
+	}
+}
+
+func TestTagPolicyDeniesUnlistedSender(t *testing.T) {
+	policy, err := NewTagPolicy([]TagRule{
+		{Sources: []string{"10.10.0.0/16"}, Tags: []string{"*"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !policy.Allows(net.ParseIP("10.10.1.1"), "ANYTHING") {
+		t.Fatal("expected wildcard rule to allow any tag")
+	}
+	if policy.Allows(net.ParseIP("203.0.113.50"), "INSIGHTS") {
+		t.Fatal("expected sender without a rule to be denied")
+	}
+}
+
+func TestTagPolicyRejectsInvalidSource(t *testing.T) {
+	if _, err := NewTagPolicy([]TagRule{{Sources: []string{"not-an-ip"}, Tags: []string{"ALARM"}}}); err == nil {
+		t.Fatal("expected invalid source to be rejected")
+	}
+}
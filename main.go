@@ -5,11 +5,23 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
+	"logvault/alarm"
+	"logvault/archive"
+	"logvault/autoresolve"
 	"logvault/config"
+	"logvault/escalation"
+	"logvault/incident"
+	"logvault/maintenance"
 	"logvault/redis"
+	"logvault/retention"
+	"logvault/silence"
+	"logvault/spool"
+	"logvault/store"
 	"logvault/syslog"
+	"logvault/trash"
 	"logvault/web"
 )
 
@@ -24,19 +36,136 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Init Redis
-	rdb, err := redis.NewRedisClient(appConfig.Redis.Address, appConfig.Redis.Password, appConfig.Redis.DB)
+	// Init alarm storage
+	var alarms store.AlarmStore
+	var events store.EventLog
+	var trashed store.AlarmStore
+	var silenced store.AlarmStore
+	var grouped store.AlarmStore
+	var spooler *spool.Spool
+	switch strings.ToLower(appConfig.Storage.Backend) {
+	case "memory":
+		alarms = store.NewMemoryStore()
+		events = store.NewMemoryEventLog(appConfig.Events.MaxLen)
+		trashed = store.NewMemoryStore()
+		silenced = store.NewMemoryStore()
+		grouped = store.NewMemoryStore()
+		log.Println("Using in-memory alarm storage. Alarms will be lost on restart.")
+	case "redis", "":
+		rdb, connErr := redis.Connect(redis.OptionsFromConfig(appConfig), appConfig.Redis.StartupTimeout)
+		if rdb == nil || (connErr != nil && !appConfig.Spool.Enabled) {
+			log.Fatalf("Failed to connect to Redis: %v", connErr)
+		}
+		redisAlarms := redis.NewAlarmStore(rdb)
+		if connErr != nil {
+			log.Printf("Failed to connect to Redis, starting in degraded mode: %v", connErr)
+		} else {
+			log.Println("Successfully connected to Redis")
+			if added, err := redisAlarms.SyncIndex(ctx); err != nil {
+				log.Printf("Failed to sync alarm index: %v", err)
+			} else if added > 0 {
+				log.Printf("Added %d existing alarms to the alarm index", added)
+			}
+		}
+		alarms = redisAlarms
+		events = redis.NewEventLog(rdb, appConfig.Events.MaxLen)
+		trashed = redis.NewTrashStore(rdb)
+		silenced = redis.NewSilenceStore(rdb)
+		grouped = redis.NewIncidentStore(rdb)
+
+		// Spool writes to local disk while Redis is unreachable
+		if appConfig.Spool.Enabled {
+			spooler, err = spool.Open(appConfig.Spool.Dir, alarms, events, rdb.Ping)
+			if err != nil {
+				if connErr != nil {
+					log.Fatalf("Failed to open spool while Redis is unreachable: %v", err)
+				}
+				log.Printf("Failed to open spool, continuing without it: %v", err)
+			} else {
+				if _, err := spooler.Replay(ctx); err != nil {
+					log.Printf("SPOOL: Replay stopped: %v", err)
+				}
+				alarms = spooler.AlarmStore()
+				events = spooler.EventLog()
+				go spooler.Run(appConfig.Spool.ReplayInterval)
+			}
+		}
+	default:
+		log.Fatalf("Unknown storage.backend %q: expected redis or memory", appConfig.Storage.Backend)
+	}
+
+	// Upgrade alarms written by earlier releases as they are read
+	alarms = alarm.UpgradeOnRead(alarms)
+
+	// Start retention sweeper
+	go retention.NewSweeper(alarms, events, appConfig).Run(appConfig.Retention.Interval)
+
+	// Resolve alarms that stopped recurring
+	go autoresolve.NewResolver(alarms, events, appConfig).Run(appConfig.AutoResolve.Interval)
+
+	// Escalate alarms nobody acknowledges
+	escalator, err := escalation.New(alarms, events, appConfig)
+	if err != nil {
+		log.Fatalf("Invalid escalation configuration: %v", err)
+	}
+	go escalator.Run(appConfig.Escalation.Interval)
+
+	// Keep deleted alarms in the recycle bin
+	var bin *trash.Bin
+	if appConfig.Trash.Enabled {
+		bin = trash.New(alarms, trashed, events, appConfig.Trash.Retention)
+		go bin.Run(appConfig.Trash.PurgeInterval)
+	}
+
+	// Load notification silences and maintenance windows
+	windows := make([]maintenance.WindowConfig, 0, len(appConfig.Maintenance.Windows))
+	for _, w := range appConfig.Maintenance.Windows {
+		windows = append(windows, maintenance.WindowConfig{
+			Name:     w.Name,
+			Schedule: w.Schedule,
+			Duration: w.Duration,
+			Timezone: w.Timezone,
+			Senders:  w.Senders,
+			Tags:     w.Tags,
+		})
+	}
+	calendar, err := maintenance.New(windows)
+	if err != nil {
+		log.Fatalf("Invalid maintenance.windows configuration: %v", err)
+	}
+	silences := silence.New(silenced, appConfig.Silences.Retention, calendar)
+	if err := silences.Load(ctx); err != nil {
+		log.Printf("Failed to load silences: %v", err)
+	}
+	go silences.Run(appConfig.Silences.RefreshInterval)
+
+	// Group related alarms into incidents
+	rules := make([]incident.Rule, 0, len(appConfig.Incidents.Rules))
+	for _, r := range appConfig.Incidents.Rules {
+		rules = append(rules, incident.Rule{Name: r.Name, Tag: r.Tag, Fields: r.Fields, Window: r.Window})
+	}
+	incidents, err := incident.New(alarms, grouped, events, rules)
 	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+		log.Fatalf("Invalid incidents.rules configuration: %v", err)
+	}
+
+	// Open local event archive
+	var archiver *archive.Writer
+	if appConfig.Archive.Enabled {
+		archiver, err = archive.Open(appConfig.Archive.Dir, appConfig.Archive.MaxAge, appConfig.Archive.MaxSizeMB*1024*1024)
+		if err != nil {
+			log.Fatalf("Failed to open archive: %v", err)
+		}
+		go archiver.Run(appConfig.Archive.FlushInterval)
+		log.Printf("Archiving received events to %s", appConfig.Archive.Dir)
 	}
-	log.Println("Successfully connected to Redis")
 
 	// Start Syslog server
-	syslogServer := syslog.StartServer(rdb, appConfig)
+	syslogServer := syslog.StartServer(alarms, events, archiver, silences, incidents, appConfig)
 	log.Printf("Syslog server started. Listening on %s:%d", appConfig.Syslog.Host, appConfig.Syslog.Port)
 
 	// Start Web server
-	go web.StartServer(rdb, appConfig)
+	go web.StartServer(alarms, events, bin, silences, incidents, spooler, appConfig)
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -45,4 +174,7 @@ func main() {
 
 	log.Println("Shutting down logvault...")
 	syslogServer.Kill()
+	if err := archiver.Close(); err != nil {
+		log.Printf("Failed to close archive: %v", err)
+	}
 }
@@ -0,0 +1,211 @@
+package maintenance
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"logvault/alarm"
+)
+
+// WindowConfig describes a recurring maintenance window as configured in
+// maintenance.windows.
+type WindowConfig struct {
+	Name     string
+	Schedule string
+	Duration time.Duration
+	Timezone string
+	Senders  []string
+	Tags     []string
+}
+
+// Window is a recurring maintenance window. It starts whenever its schedule
+// fires and lasts for its duration. It applies to alarms from its senders,
+// given as IPs, CIDR ranges or hostnames, and with its tags; when both are
+// set an alarm must match both.
+type Window struct {
+	Name     string
+	schedule *Schedule
+	expr     string
+	duration time.Duration
+	networks []*net.IPNet
+	hosts    []string
+	tags     []string
+}
+
+// Occurrence is one run of a maintenance window.
+type Occurrence struct {
+	Window   string    `json:"window"`
+	Schedule string    `json:"schedule"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Senders  []string  `json:"senders,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+}
+
+// Calendar holds the configured maintenance windows. A nil Calendar has no
+// windows.
+type Calendar struct {
+	windows []*Window
+}
+
+// New parses the configured windows. Every window needs a name, a valid
+// schedule, a positive duration and at least one sender or tag.
+func New(configs []WindowConfig) (*Calendar, error) {
+	c := &Calendar{}
+	for _, cfg := range configs {
+		w, err := newWindow(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window %q: %w", cfg.Name, err)
+		}
+		c.windows = append(c.windows, w)
+	}
+	return c, nil
+}
+
+func newWindow(cfg WindowConfig) (*Window, error) {
+	name := strings.TrimSpace(cfg.Name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if cfg.Duration <= 0 {
+		return nil, fmt.Errorf("duration must be positive")
+	}
+
+	var loc *time.Location
+	if cfg.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+	}
+	schedule, err := ParseSchedule(cfg.Schedule, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Window{Name: name, schedule: schedule, expr: cfg.Schedule, duration: cfg.Duration}
+	for _, sender := range cfg.Senders {
+		sender = strings.TrimSpace(sender)
+		switch {
+		case sender == "":
+		case strings.Contains(sender, "/"):
+			_, network, err := net.ParseCIDR(sender)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sender CIDR %q", sender)
+			}
+			w.networks = append(w.networks, network)
+		case net.ParseIP(sender) != nil:
+			ip := net.ParseIP(sender)
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			w.networks = append(w.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		default:
+			w.hosts = append(w.hosts, strings.ToLower(sender))
+		}
+	}
+	for _, tag := range cfg.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			w.tags = append(w.tags, strings.ToUpper(tag))
+		}
+	}
+	if len(w.networks) == 0 && len(w.hosts) == 0 && len(w.tags) == 0 {
+		return nil, fmt.Errorf("at least one sender or tag is required")
+	}
+	return w, nil
+}
+
+// Applies reports whether the window covers alarms like doc, ignoring time.
+func (w *Window) Applies(doc alarm.Document) bool {
+	if len(w.tags) > 0 && !contains(w.tags, strings.ToUpper(doc.Tag)) {
+		return false
+	}
+	if len(w.networks) == 0 && len(w.hosts) == 0 {
+		return true
+	}
+	if ip := net.ParseIP(doc.Source.IP); ip != nil {
+		for _, network := range w.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return doc.Source.Hostname != "" && contains(w.hosts, strings.ToLower(doc.Source.Hostname))
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Window) occurrence(start time.Time) Occurrence {
+	o := Occurrence{Window: w.Name, Schedule: w.expr, Start: start, End: start.Add(w.duration), Tags: w.tags}
+	for _, network := range w.networks {
+		o.Senders = append(o.Senders, network.String())
+	}
+	o.Senders = append(o.Senders, w.hosts...)
+	return o
+}
+
+// current returns the occurrence of w in progress at t, if any.
+func (w *Window) current(t time.Time) (Occurrence, bool) {
+	// The latest start that still covers t is within the last duration.
+	start := w.schedule.Next(t.Add(-w.duration))
+	if start.IsZero() || start.After(t) {
+		return Occurrence{}, false
+	}
+	return w.occurrence(start), true
+}
+
+// Active returns the maintenance window in progress at t that applies to
+// doc, or nil. When several apply, the one ending last is returned.
+func (c *Calendar) Active(doc alarm.Document, t time.Time) *Occurrence {
+	if c == nil {
+		return nil
+	}
+	var match *Occurrence
+	for _, w := range c.windows {
+		if !w.Applies(doc) {
+			continue
+		}
+		if o, ok := w.current(t); ok && (match == nil || o.End.After(match.End)) {
+			match = &o
+		}
+	}
+	return match
+}
+
+// maxOccurrences bounds how many runs of one window Upcoming lists.
+const maxOccurrences = 500
+
+// Upcoming lists the occurrences in progress at from or starting before
+// until, ordered by start.
+func (c *Calendar) Upcoming(from, until time.Time) []Occurrence {
+	if c == nil {
+		return nil
+	}
+	var list []Occurrence
+	for _, w := range c.windows {
+		t := from.Add(-w.duration)
+		for i := 0; i < maxOccurrences; i++ {
+			start := w.schedule.Next(t)
+			if start.IsZero() || !start.Before(until) {
+				break
+			}
+			list = append(list, w.occurrence(start))
+			t = start
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Start.Before(list[j].Start)
+	})
+	return list
+}
@@ -0,0 +1,121 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"logvault/alarm"
+)
+
+func TestScheduleNext(t *testing.T) {
+	loc := time.UTC
+	from := time.Date(2026, 3, 4, 10, 30, 0, 0, loc) // Wednesday
+
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 3, 4, 10, 45, 0, 0, loc)},
+		{"0 2 * * sat", time.Date(2026, 3, 7, 2, 0, 0, 0, loc)},
+		{"0 2 * * 0", time.Date(2026, 3, 8, 2, 0, 0, 0, loc)},
+		{"0 2 * * 7", time.Date(2026, 3, 8, 2, 0, 0, 0, loc)},
+		{"30 22 1 * *", time.Date(2026, 4, 1, 22, 30, 0, 0, loc)},
+		{"0 9-17/4 * * 1-5", time.Date(2026, 3, 4, 13, 0, 0, 0, loc)},
+		{"0 0 1 jan,jul *", time.Date(2026, 7, 1, 0, 0, 0, 0, loc)},
+		// Both day fields restricted: either matches.
+		{"0 0 15 * fri", time.Date(2026, 3, 6, 0, 0, 0, 0, loc)},
+	}
+	for _, c := range cases {
+		s, err := ParseSchedule(c.expr, loc)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.expr, err)
+		}
+		if got := s.Next(from); !got.Equal(c.want) {
+			t.Fatalf("%s: expected %s, got %s", c.expr, c.want, got)
+		}
+	}
+
+	never, _ := ParseSchedule("0 0 30 feb *", loc)
+	if got := never.Next(from); !got.IsZero() {
+		t.Fatalf("expected a schedule that never fires to return zero, got %s", got)
+	}
+}
+
+func TestParseScheduleRejectsInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "x * * * *"} {
+		if _, err := ParseSchedule(expr, nil); err == nil {
+			t.Fatalf("expected %q to be rejected", expr)
+		}
+	}
+}
+
+func patchWindow(t *testing.T) *Calendar {
+	t.Helper()
+	c, err := New([]WindowConfig{{
+		Name:     "site-a patching",
+		Schedule: "0 2 * * sat",
+		Duration: 4 * time.Hour,
+		Timezone: "UTC",
+		Senders:  []string{"10.1.0.0/16", "fw-a.example.com"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c
+}
+
+func TestCalendarActiveDuringWindowForScopedSenders(t *testing.T) {
+	c := patchWindow(t)
+	doc := alarm.New("a1", "ALARM", time.Time{})
+	doc.Source.IP = "10.1.2.3"
+
+	during := time.Date(2026, 3, 7, 3, 15, 0, 0, time.UTC)
+	o := c.Active(doc, during)
+	if o == nil || o.Window != "site-a patching" || !o.Start.Equal(time.Date(2026, 3, 7, 2, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the Saturday window to be active, got %+v", o)
+	}
+	if c.Active(doc, time.Date(2026, 3, 7, 6, 0, 0, 0, time.UTC)) != nil {
+		t.Fatal("expected the window to end after its duration")
+	}
+
+	other := alarm.New("a2", "ALARM", time.Time{})
+	other.Source.IP = "10.2.0.1"
+	if c.Active(other, during) != nil {
+		t.Fatal("expected senders outside the window's scope not to match")
+	}
+	byHost := alarm.New("a3", "ALARM", time.Time{})
+	byHost.Source.Hostname = "FW-A.example.com"
+	if c.Active(byHost, during) == nil {
+		t.Fatal("expected the sender hostname to match")
+	}
+
+	var none *Calendar
+	if none.Active(doc, during) != nil {
+		t.Fatal("expected a nil calendar to match nothing")
+	}
+}
+
+func TestCalendarUpcomingIncludesWindowInProgress(t *testing.T) {
+	c := patchWindow(t)
+	from := time.Date(2026, 3, 7, 3, 0, 0, 0, time.UTC)
+
+	upcoming := c.Upcoming(from, from.AddDate(0, 0, 14))
+	if len(upcoming) != 3 {
+		t.Fatalf("expected the current and two following windows, got %+v", upcoming)
+	}
+	if !upcoming[0].Start.Equal(time.Date(2026, 3, 7, 2, 0, 0, 0, time.UTC)) || !upcoming[2].End.Equal(time.Date(2026, 3, 21, 6, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected occurrences %+v", upcoming)
+	}
+	if len(upcoming[0].Senders) != 2 {
+		t.Fatalf("expected the window's scope to be listed, got %+v", upcoming[0].Senders)
+	}
+}
+
+func TestNewRejectsUnscopedWindows(t *testing.T) {
+	if _, err := New([]WindowConfig{{Name: "all", Schedule: "0 2 * * *", Duration: time.Hour}}); err == nil {
+		t.Fatal("expected a window without senders or tags to be rejected")
+	}
+	if _, err := New([]WindowConfig{{Name: "bad", Schedule: "0 2 * * *", Duration: time.Hour, Tags: []string{"ALARM"}, Timezone: "Mars/Olympus"}}); err == nil {
+		t.Fatal("expected an unknown timezone to be rejected")
+	}
+}
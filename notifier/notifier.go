@@ -4,34 +4,72 @@ import (
 	"bytes"
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"logvault/config"
 )
 
+// ShouldTrigger reports whether alarms with tag are sent to the external API,
+// given the comma-separated external_api.trigger_tags.
+func ShouldTrigger(tag string, triggerTags string) bool {
+	if triggerTags == "" {
+		return false
+	}
+	incomingTag := strings.ToUpper(tag)
+	tags := strings.Split(triggerTags, ",")
+	for _, t := range tags {
+		if incomingTag == strings.ToUpper(strings.TrimSpace(t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Target is an HTTP endpoint notifications are sent to as JSON.
+type Target struct {
+	URL         string
+	Method      string
+	BearerToken string
+}
+
 // CallExternalAPI makes an HTTP request to the configured external API
 func CallExternalAPI(appConfig config.Config, payload interface{}) {
 	if !appConfig.ExternalAPI.Enabled {
 		return // External API integration is disabled
 	}
 
+	target := Target{URL: appConfig.ExternalAPI.URL, Method: appConfig.ExternalAPI.Method, BearerToken: appConfig.ExternalAPI.BearerToken}
+	if err := Send(target, payload); err != nil {
+		log.Printf("Error calling external API: %v", err)
+		return
+	}
+	log.Printf("Successfully called external API")
+}
+
+// Send posts payload to target and returns an error if the request fails or
+// is answered with an error status.
+func Send(target Target, payload interface{}) error {
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("Error marshalling external API payload: %v", err)
-		return
+		return fmt.Errorf("marshal payload: %w", err)
 	}
 
-	req, err := http.NewRequest(appConfig.ExternalAPI.Method, appConfig.ExternalAPI.URL, bytes.NewBuffer(jsonPayload))
+	method := target.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, target.URL, bytes.NewBuffer(jsonPayload))
 	if err != nil {
-		log.Printf("Error creating external API request: %v", err)
-		return
+		return fmt.Errorf("create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	if appConfig.ExternalAPI.BearerToken != "" {
-		req.Header.Set("Authorization", "Bearer "+appConfig.ExternalAPI.BearerToken)
+	if target.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+target.BearerToken)
 	}
 
 	// Create a custom transport to skip TLS verification
@@ -45,14 +83,12 @@ func CallExternalAPI(appConfig config.Config, payload interface{}) {
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Printf("Error calling external API: %v", err)
-		return
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		log.Printf("External API call failed with status: %s", resp.Status)
-	} else {
-		log.Printf("Successfully called external API, status: %s", resp.Status)
+		return fmt.Errorf("failed with status: %s", resp.Status)
 	}
+	return nil
 }
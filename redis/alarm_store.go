@@ -0,0 +1,526 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"logvault/store"
+)
+
+// Alarms are indexed by a sorted set scored by received time (Unix ms), by
+// one sorted set per tag and by one per fingerprint, so pages can be read
+// newest-first without scanning the keyspace. Hashes map each alarm ID to its
+// tag and fingerprint for index maintenance. All names are relative to the
+// client's namespace.
+const (
+	alarmTimeIndexKey           = "index:alarms:time"
+	alarmTagIndexPrefix         = "index:alarms:tag:"
+	alarmTagsKey                = "index:alarms:tags"
+	alarmFingerprintIndexPrefix = "index:alarms:fingerprint:"
+	alarmFingerprintsKey        = "index:alarms:fingerprints"
+
+	// legacyAlarmIndexKey is the unordered ID set used before the time index.
+	legacyAlarmIndexKey = "index:alarms"
+)
+
+// maxWriteAttempts bounds how often Put and Delete retry when another client
+// writes the same alarm between their read of the indexes and their
+// transaction.
+const maxWriteAttempts = 10
+
+// AlarmStore implements store.AlarmStore on top of Redis string keys.
+type AlarmStore struct {
+	client *RedisClient
+}
+
+func NewAlarmStore(client *RedisClient) *AlarmStore {
+	return &AlarmStore{client: client}
+}
+
+// trashSubspace holds deleted alarms, with their own indexes.
+const trashSubspace = "trash"
+
+// NewTrashStore returns the store for deleted alarms, kept under "trash:"
+// within the client's namespace.
+func NewTrashStore(client *RedisClient) *AlarmStore {
+	return NewAlarmStore(client.Subspace(trashSubspace))
+}
+
+// silenceSubspace holds notification silences.
+const silenceSubspace = "silences"
+
+// NewSilenceStore returns the store for silences, kept under "silences:"
+// within the client's namespace.
+func NewSilenceStore(client *RedisClient) *AlarmStore {
+	return NewAlarmStore(client.Subspace(silenceSubspace))
+}
+
+// incidentSubspace holds incidents grouping related alarms.
+const incidentSubspace = "incidents"
+
+// NewIncidentStore returns the store for incidents, kept under "incidents:"
+// within the client's namespace.
+func NewIncidentStore(client *RedisClient) *AlarmStore {
+	return NewAlarmStore(client.Subspace(incidentSubspace))
+}
+
+func (s *AlarmStore) alarmKey(id string) string {
+	return s.client.Key(store.Key(id))
+}
+
+func (s *AlarmStore) tagIndexKey(tag string) string {
+	return s.client.Key(alarmTagIndexPrefix + strings.ToUpper(tag))
+}
+
+func (s *AlarmStore) fingerprintIndexKey(fingerprint string) string {
+	return s.client.Key(alarmFingerprintIndexPrefix + fingerprint)
+}
+
+// SyncIndex adds alarms written before the indexes existed, or by older
+// versions, to the time, tag and fingerprint indexes. It walks the keyspace once with SCAN.
+// Alarms without a "received_at" field are indexed at the time of the sync.
+func (s *AlarmStore) SyncIndex(ctx context.Context) (int, error) {
+	added := 0
+	now := time.Now()
+	err := s.client.ScanKeys(ctx, s.client.Key(store.KeyPrefix+"*"), func(keys []string) error {
+		values, err := s.client.MGet(ctx, keys)
+		if err != nil {
+			return err
+		}
+
+		pipe := s.client.client.Pipeline()
+		adds := make([]*redis.IntCmd, 0, len(values))
+		for key, value := range values {
+			id := strings.TrimPrefix(s.client.Unkey(key), store.KeyPrefix)
+			tag, receivedAt := indexFieldsFromValue(value, now)
+			member := &redis.Z{Score: float64(receivedAt.UnixMilli()), Member: id}
+			adds = append(adds, pipe.ZAddNX(ctx, s.client.Key(alarmTimeIndexKey), member))
+			pipe.ZAddNX(ctx, s.tagIndexKey(tag), member)
+			pipe.HSetNX(ctx, s.client.Key(alarmTagsKey), id, tag)
+			if fp := store.FingerprintOf(value); fp != "" {
+				pipe.ZAddNX(ctx, s.fingerprintIndexKey(fp), member)
+				pipe.HSetNX(ctx, s.client.Key(alarmFingerprintsKey), id, fp)
+			}
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		for _, cmd := range adds {
+			added += int(cmd.Val())
+		}
+		return nil
+	})
+	if err != nil {
+		return added, fmt.Errorf("failed to sync alarm index: %w", err)
+	}
+
+	s.client.client.Del(ctx, s.client.Key(legacyAlarmIndexKey))
+	return added, nil
+}
+
+func indexFieldsFromValue(value string, fallback time.Time) (string, time.Time) {
+	var doc struct {
+		Tag        string `json:"tag"`
+		ReceivedAt string `json:"received_at"`
+	}
+	if err := json.Unmarshal([]byte(value), &doc); err != nil {
+		return "", fallback
+	}
+	receivedAt, err := time.Parse(time.RFC3339Nano, doc.ReceivedAt)
+	if err != nil {
+		return doc.Tag, fallback
+	}
+	return doc.Tag, receivedAt
+}
+
+// Put writes the alarm and moves its index entries. Every write to an alarm
+// also writes its key, so watching the key makes the read of its old tag and
+// fingerprint and the index move one atomic step.
+func (s *AlarmStore) Put(ctx context.Context, rec store.Record) error {
+	for attempt := 1; ; attempt++ {
+		err := s.client.client.Watch(ctx, func(tx *redis.Tx) error {
+			return s.write(ctx, tx, rec)
+		}, s.alarmKey(rec.ID))
+		if !errors.Is(err, redis.TxFailedErr) || attempt == maxWriteAttempts {
+			return err
+		}
+	}
+}
+
+// Swap writes rec only if the alarm key still holds old.Value.
+func (s *AlarmStore) Swap(ctx context.Context, old, rec store.Record) error {
+	err := s.client.client.Watch(ctx, func(tx *redis.Tx) error {
+		value, err := tx.Get(ctx, s.alarmKey(old.ID)).Result()
+		if errors.Is(err, redis.Nil) {
+			return store.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if value != old.Value {
+			return store.ErrConflict
+		}
+		return s.write(ctx, tx, rec)
+	}, s.alarmKey(old.ID))
+	if errors.Is(err, redis.TxFailedErr) {
+		return store.ErrConflict
+	}
+	return err
+}
+
+// write reads the alarm's indexed tag and fingerprint on the watching
+// connection and stores rec with its index entries in one transaction.
+func (s *AlarmStore) write(ctx context.Context, tx *redis.Tx, rec store.Record) error {
+	oldTag, err := tx.HGet(ctx, s.client.Key(alarmTagsKey), rec.ID).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	oldFingerprint, err := tx.HGet(ctx, s.client.Key(alarmFingerprintsKey), rec.ID).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	fingerprint := store.FingerprintOf(rec.Value)
+
+	member := &redis.Z{Score: float64(rec.ReceivedAt.UnixMilli()), Member: rec.ID}
+	_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.alarmKey(rec.ID), rec.Value, 0)
+		pipe.ZAdd(ctx, s.client.Key(alarmTimeIndexKey), member)
+		if oldTag != "" && !strings.EqualFold(oldTag, rec.Tag) {
+			pipe.ZRem(ctx, s.tagIndexKey(oldTag), rec.ID)
+		}
+		pipe.ZAdd(ctx, s.tagIndexKey(rec.Tag), member)
+		pipe.HSet(ctx, s.client.Key(alarmTagsKey), rec.ID, rec.Tag)
+		if oldFingerprint != "" && oldFingerprint != fingerprint {
+			pipe.ZRem(ctx, s.fingerprintIndexKey(oldFingerprint), rec.ID)
+		}
+		if fingerprint != "" {
+			pipe.ZAdd(ctx, s.fingerprintIndexKey(fingerprint), member)
+			pipe.HSet(ctx, s.client.Key(alarmFingerprintsKey), rec.ID, fingerprint)
+		} else {
+			pipe.HDel(ctx, s.client.Key(alarmFingerprintsKey), rec.ID)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *AlarmStore) Get(ctx context.Context, id string) (store.Record, error) {
+	var value *redis.StringCmd
+	var score *redis.FloatCmd
+	var tag *redis.StringCmd
+	_, err := s.client.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		value = pipe.Get(ctx, s.alarmKey(id))
+		score = pipe.ZScore(ctx, s.client.Key(alarmTimeIndexKey), id)
+		tag = pipe.HGet(ctx, s.client.Key(alarmTagsKey), id)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return store.Record{}, err
+	}
+	if errors.Is(value.Err(), redis.Nil) {
+		return store.Record{}, store.ErrNotFound
+	}
+
+	rec := store.Record{ID: id, Tag: tag.Val(), Value: value.Val()}
+	if score.Err() == nil {
+		rec.ReceivedAt = time.UnixMilli(int64(score.Val()))
+	}
+	return rec, nil
+}
+
+func (s *AlarmStore) List(ctx context.Context) ([]store.Record, error) {
+	page, err := s.Query(ctx, store.Query{})
+	return page.Records, err
+}
+
+// Delete removes the alarms and their index entries. Like Put, it reads the
+// indexed tags and fingerprints while watching the alarm keys, so a Put
+// racing the delete cannot leave an index entry behind.
+func (s *AlarmStore) Delete(ctx context.Context, ids ...string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.alarmKey(id)
+	}
+
+	for attempt := 1; ; attempt++ {
+		var deleted int
+		err := s.client.client.Watch(ctx, func(tx *redis.Tx) error {
+			n, err := s.remove(ctx, tx, ids, keys)
+			deleted = n
+			return err
+		}, keys...)
+		if err == nil {
+			return deleted, nil
+		}
+		if !errors.Is(err, redis.TxFailedErr) || attempt == maxWriteAttempts {
+			return 0, err
+		}
+	}
+}
+
+// remove reads the alarms' indexed tags and fingerprints on the watching
+// connection and deletes the alarms with their index entries in one
+// transaction.
+func (s *AlarmStore) remove(ctx context.Context, tx *redis.Tx, ids, keys []string) (int, error) {
+	tags, fingerprints, err := s.indexedFields(ctx, tx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	var del *redis.IntCmd
+	_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		del = pipe.Del(ctx, keys...)
+		s.unindex(ctx, pipe, ids, tags, fingerprints)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(del.Val()), nil
+}
+
+// prune removes the index entries of alarms whose key no longer exists. It
+// never deletes an alarm key: it watches the keys and leaves out IDs that
+// were written again since the index was read, so a Put racing a query keeps
+// its alarm. Pruning is best effort; a lost race is left to a later query.
+func (s *AlarmStore) prune(ctx context.Context, ids []string) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.alarmKey(id)
+	}
+
+	err := s.client.client.Watch(ctx, func(tx *redis.Tx) error {
+		var missing []string
+		for i, id := range ids {
+			n, err := tx.Exists(ctx, keys[i]).Result()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+
+		tags, fingerprints, err := s.indexedFields(ctx, tx, missing)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			s.unindex(ctx, pipe, missing, tags, fingerprints)
+			return nil
+		})
+		return err
+	}, keys...)
+	if err != nil && !errors.Is(err, redis.TxFailedErr) {
+		log.Printf("Failed to prune stale alarm index entries: %v", err)
+	}
+}
+
+// indexedFields reads the indexed tags and fingerprints of the alarms on the
+// watching connection.
+func (s *AlarmStore) indexedFields(ctx context.Context, tx *redis.Tx, ids []string) ([]interface{}, []interface{}, error) {
+	tags, err := tx.HMGet(ctx, s.client.Key(alarmTagsKey), ids...).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+	fingerprints, err := tx.HMGet(ctx, s.client.Key(alarmFingerprintsKey), ids...).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+	return tags, fingerprints, nil
+}
+
+// unindex queues the removal of the alarms' index entries.
+func (s *AlarmStore) unindex(ctx context.Context, pipe redis.Pipeliner, ids []string, tags, fingerprints []interface{}) {
+	members := make([]interface{}, len(ids))
+	for i, id := range ids {
+		members[i] = id
+	}
+
+	pipe.ZRem(ctx, s.client.Key(alarmTimeIndexKey), members...)
+	pipe.HDel(ctx, s.client.Key(alarmTagsKey), ids...)
+	pipe.HDel(ctx, s.client.Key(alarmFingerprintsKey), ids...)
+	for i, tag := range tags {
+		if tag, ok := tag.(string); ok {
+			pipe.ZRem(ctx, s.tagIndexKey(tag), ids[i])
+		}
+	}
+	for i, fp := range fingerprints {
+		if fp, ok := fp.(string); ok {
+			pipe.ZRem(ctx, s.fingerprintIndexKey(fp), ids[i])
+		}
+	}
+}
+
+func (s *AlarmStore) Query(ctx context.Context, q store.Query) (store.Page, error) {
+	var cursor *store.Cursor
+	if q.Cursor != "" {
+		c, err := store.ParseCursor(q.Cursor)
+		if err != nil {
+			return store.Page{}, err
+		}
+		cursor = &c
+	}
+
+	indexKey := s.client.Key(alarmTimeIndexKey)
+	switch {
+	case q.Fingerprint != "":
+		indexKey = s.fingerprintIndexKey(q.Fingerprint)
+	case q.Tag != "":
+		indexKey = s.tagIndexKey(q.Tag)
+	}
+
+	min, max := "-inf", "+inf"
+	if !q.Since.IsZero() {
+		min = strconv.FormatInt(q.Since.UnixMilli(), 10)
+	}
+	maxMs := int64(-1)
+	if !q.Until.IsZero() {
+		maxMs = q.Until.UnixMilli()
+	}
+	if cursor != nil && (maxMs < 0 || cursor.ReceivedAt < maxMs) {
+		maxMs = cursor.ReceivedAt
+	}
+	if maxMs >= 0 {
+		max = strconv.FormatInt(maxMs, 10)
+	}
+
+	var records []store.Record
+	var stale []string
+	// Stale entries are pruned only after the walk so offsets stay valid.
+	defer func() {
+		if len(stale) > 0 {
+			s.prune(ctx, stale)
+		}
+	}()
+
+	var offset int64
+	for {
+		members, err := s.client.client.ZRevRangeByScoreWithScores(ctx, indexKey, &redis.ZRangeBy{
+			Min:    min,
+			Max:    max,
+			Offset: offset,
+			Count:  scanBatchSize,
+		}).Result()
+		if err != nil {
+			return store.Page{}, fmt.Errorf("failed to read alarm index: %w", err)
+		}
+		offset += int64(len(members))
+
+		batch, missing, err := s.loadIndexed(ctx, members, cursor)
+		if err != nil {
+			return store.Page{}, err
+		}
+		stale = append(stale, missing...)
+		for _, rec := range batch {
+			if q.Matches(rec) {
+				records = append(records, rec)
+			}
+		}
+
+		if q.Limit > 0 && len(records) > q.Limit {
+			return store.Page{
+				Records:    records[:q.Limit],
+				NextCursor: store.CursorFor(records[q.Limit-1]),
+			}, nil
+		}
+		if len(members) < scanBatchSize {
+			return store.Page{Records: records}, nil
+		}
+	}
+}
+
+// loadIndexed fetches the alarms for a batch of index entries, preserving
+// index order. It also returns the IDs whose alarm key no longer exists.
+func (s *AlarmStore) loadIndexed(ctx context.Context, members []redis.Z, cursor *store.Cursor) ([]store.Record, []string, error) {
+	ids := make([]string, 0, len(members))
+	scores := make(map[string]int64, len(members))
+	for _, z := range members {
+		id, ok := z.Member.(string)
+		if !ok {
+			continue
+		}
+		score := int64(z.Score)
+		if cursor != nil && !cursor.Includes(score, id) {
+			continue
+		}
+		ids = append(ids, id)
+		scores[id] = score
+	}
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.alarmKey(id)
+	}
+	values, err := s.client.MGet(ctx, keys)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get alarms: %w", err)
+	}
+	tags, err := s.client.client.HMGet(ctx, s.client.Key(alarmTagsKey), ids...).Result()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get alarm tags: %w", err)
+	}
+
+	records := make([]store.Record, 0, len(ids))
+	var stale []string
+	for i, id := range ids {
+		value, ok := values[s.alarmKey(id)]
+		if !ok {
+			stale = append(stale, id) // Key is gone but the index still lists it
+			continue
+		}
+		tag, _ := tags[i].(string)
+		records = append(records, store.Record{
+			ID:         id,
+			Tag:        tag,
+			ReceivedAt: time.UnixMilli(scores[id]),
+			Value:      value,
+		})
+	}
+
+	return records, stale, nil
+}
+
+// Dump returns Logvault's string keys in the client's namespace, with the
+// namespace stripped. Keys not matching Logvault's key patterns are left
+// out, even without a namespace.
+func (s *AlarmStore) Dump(ctx context.Context) (map[string]string, error) {
+	data := make(map[string]string)
+	// Only Logvault's own keys are scanned, so other data in a shared
+	// database is never dumped, with or without a namespace.
+	for _, pattern := range namespacedPatterns {
+		err := s.client.ScanKeys(ctx, s.client.Key(pattern), func(keys []string) error {
+			// Keys holding non-string values (like the indexes) are skipped by MGET
+			values, err := s.client.MGet(ctx, keys)
+			if err != nil {
+				return err
+			}
+			for k, v := range values {
+				data[s.client.Unkey(k)] = v
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to dump keys: %w", err)
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,36 @@
+package redis
+
+import "logvault/config"
+
+// OptionsFromConfig maps the redis section of the application config to
+// client options.
+func OptionsFromConfig(appConfig config.Config) Options {
+	c := appConfig.Redis
+	return Options{
+		Namespace:        c.Namespace,
+		Addr:             c.Address,
+		Username:         c.Username,
+		Password:         c.Password,
+		DB:               c.DB,
+		MasterName:       c.Sentinel.MasterName,
+		SentinelAddrs:    c.Sentinel.Addresses,
+		SentinelUsername: c.Sentinel.Username,
+		SentinelPassword: c.Sentinel.Password,
+		TLS: TLSOptions{
+			Enabled:            c.TLS.Enabled,
+			CAFile:             c.TLS.CAFile,
+			CertFile:           c.TLS.CertFile,
+			KeyFile:            c.TLS.KeyFile,
+			ServerName:         c.TLS.ServerName,
+			InsecureSkipVerify: c.TLS.InsecureSkipVerify,
+		},
+		PoolSize:     c.PoolSize,
+		MinIdleConns: c.MinIdleConns,
+		PoolTimeout:  c.PoolTimeout,
+		IdleTimeout:  c.IdleTimeout,
+		DialTimeout:  c.DialTimeout,
+		ReadTimeout:  c.ReadTimeout,
+		WriteTimeout: c.WriteTimeout,
+		MaxRetries:   c.MaxRetries,
+	}
+}
@@ -0,0 +1,167 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"logvault/store"
+)
+
+const eventStreamKey = "events:alarms"
+
+// EventLog implements store.EventLog with a Redis Stream that is trimmed to
+// roughly maxLen entries on every append.
+type EventLog struct {
+	client *RedisClient
+	maxLen int64
+}
+
+func NewEventLog(client *RedisClient, maxLen int) *EventLog {
+	return &EventLog{client: client, maxLen: int64(maxLen)}
+}
+
+func (l *EventLog) Append(ctx context.Context, events ...store.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	_, err := l.client.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, e := range events {
+			values := map[string]interface{}{
+				"type":     e.Type,
+				"alarm_id": e.AlarmID,
+				"tag":      e.Tag,
+				"actor":    e.Actor,
+				"message":  e.Message,
+				"data":     e.Data,
+			}
+			if e.OccurredAt != nil {
+				values["occurred_at"] = e.OccurredAt.UTC().Format(time.RFC3339Nano)
+			}
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: l.client.Key(eventStreamKey),
+				MaxLen: l.maxLen,
+				Approx: true,
+				ID:     "*",
+				Values: values,
+			})
+		}
+		return nil
+	})
+	return err
+}
+
+func (l *EventLog) Events(ctx context.Context, q store.EventQuery) (store.EventPage, error) {
+	start, stop := "+", "-"
+	if !q.Until.IsZero() {
+		start = strconv.FormatInt(q.Until.UnixMilli(), 10)
+	}
+	if q.Before != "" {
+		prev, err := previousStreamID(q.Before)
+		if err != nil {
+			return store.EventPage{}, err
+		}
+		if start == "+" || streamIDLess(prev, start) {
+			start = prev
+		}
+	}
+	if !q.Since.IsZero() {
+		stop = strconv.FormatInt(q.Since.UnixMilli(), 10)
+	}
+
+	var page store.EventPage
+	for {
+		messages, err := l.client.client.XRevRangeN(ctx, l.client.Key(eventStreamKey), start, stop, scanBatchSize).Result()
+		if err != nil {
+			return store.EventPage{}, fmt.Errorf("failed to read event stream: %w", err)
+		}
+
+		for _, msg := range messages {
+			e := eventFromMessage(msg)
+			if !q.Matches(e) {
+				continue
+			}
+			if q.Limit > 0 && len(page.Events) == q.Limit {
+				page.NextCursor = page.Events[len(page.Events)-1].ID
+				return page, nil
+			}
+			page.Events = append(page.Events, e)
+		}
+
+		if len(messages) < scanBatchSize {
+			return page, nil
+		}
+		prev, err := previousStreamID(messages[len(messages)-1].ID)
+		if err != nil {
+			return store.EventPage{}, err
+		}
+		start = prev
+	}
+}
+
+func eventFromMessage(msg redis.XMessage) store.Event {
+	field := func(name string) string {
+		v, _ := msg.Values[name].(string)
+		return v
+	}
+	ms, _ := splitStreamID(msg.ID)
+	e := store.Event{
+		ID:      msg.ID,
+		Time:    time.UnixMilli(ms),
+		Type:    field("type"),
+		AlarmID: field("alarm_id"),
+		Tag:     field("tag"),
+		Actor:   field("actor"),
+		Message: field("message"),
+		Data:    field("data"),
+	}
+	if t, err := time.Parse(time.RFC3339Nano, field("occurred_at")); err == nil {
+		e.OccurredAt = &t
+	}
+	return e
+}
+
+func splitStreamID(id string) (int64, uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseInt(msPart, 10, 64)
+	seq, _ := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
+
+// previousStreamID returns the greatest stream ID strictly below id. XRANGE
+// only supports exclusive bounds from Redis 6.2, so the bound is computed.
+func previousStreamID(id string) (string, error) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, err := strconv.ParseInt(msPart, 10, 64)
+	if err != nil {
+		return "", store.ErrInvalidCursor
+	}
+	seq := uint64(0)
+	if seqPart != "" {
+		if seq, err = strconv.ParseUint(seqPart, 10, 64); err != nil {
+			return "", store.ErrInvalidCursor
+		}
+	}
+
+	if seq > 0 {
+		return fmt.Sprintf("%d-%d", ms, seq-1), nil
+	}
+	if ms == 0 {
+		return "0-0", nil
+	}
+	return fmt.Sprintf("%d-%d", ms-1, uint64(1<<64-1)), nil
+}
+
+func streamIDLess(a, b string) bool {
+	aMs, aSeq := splitStreamID(a)
+	bMs, bSeq := splitStreamID(b)
+	if !strings.Contains(b, "-") {
+		bSeq = 1<<64 - 1 // A bare millisecond bound includes every sequence
+	}
+	return aMs < bMs || (aMs == bMs && aSeq < bSeq)
+}
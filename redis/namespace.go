@@ -0,0 +1,72 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"logvault/store"
+)
+
+// namespacedPatterns match every key Logvault owns, relative to a namespace.
+var namespacedPatterns = []string{store.KeyPrefix + "*", "index:alarms*", eventStreamKey, trashSubspace + ":*", silenceSubspace + ":*", incidentSubspace + ":*"}
+
+// MigrationResult lists the keys, without namespace, that MigrateNamespace
+// moved and those it left alone because the target key already existed.
+type MigrationResult struct {
+	Moved     []string
+	Conflicts []string
+}
+
+// MigrateNamespace moves Logvault's alarms, indexes and event stream from
+// namespace from (empty for the unprefixed layout) into the client's
+// namespace using RENAMENX, so existing target keys are never overwritten.
+// With dryRun nothing is changed and the result shows what would be moved.
+// Logvault should be stopped while keys are moved.
+func (r *RedisClient) MigrateNamespace(ctx context.Context, from string, dryRun bool) (MigrationResult, error) {
+	var result MigrationResult
+	fromPrefix := namespacePrefix(from)
+	if fromPrefix == r.prefix {
+		return result, fmt.Errorf("source and target namespace are the same")
+	}
+
+	for _, pattern := range namespacedPatterns {
+		err := r.ScanKeys(ctx, fromPrefix+pattern, func(keys []string) error {
+			for _, key := range keys {
+				name := strings.TrimPrefix(key, fromPrefix)
+				target := r.Key(name)
+
+				if dryRun {
+					exists, err := r.client.Exists(ctx, target).Result()
+					if err != nil {
+						return err
+					}
+					if exists > 0 {
+						result.Conflicts = append(result.Conflicts, name)
+					} else {
+						result.Moved = append(result.Moved, name)
+					}
+					continue
+				}
+
+				moved, err := r.client.RenameNX(ctx, key, target).Result()
+				if err != nil {
+					if strings.Contains(err.Error(), "no such key") {
+						continue // Already moved; SCAN can report a key twice
+					}
+					return fmt.Errorf("failed to move %s: %w", key, err)
+				}
+				if moved {
+					result.Moved = append(result.Moved, name)
+				} else {
+					result.Conflicts = append(result.Conflicts, name)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
@@ -2,68 +2,320 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// RedisClient wraps a go-redis client. Every key Logvault reads or writes is
+// built with Key, so installs configured with different namespaces can share
+// one Redis database.
 type RedisClient struct {
 	client *redis.Client
+	prefix string
 }
 
-func NewRedisClient(addr, password string, db int) (*RedisClient, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: password,
-		DB:       db,
-	})
+// Options configures the connection to Redis. When MasterName is set the
+// client discovers the current master through the Sentinel nodes listed in
+// SentinelAddrs and Addr is ignored. Zero pool and timeout values keep the
+// go-redis defaults.
+type Options struct {
+	// Namespace is prepended, followed by a colon, to every key. An empty
+	// namespace keeps the unprefixed key layout of earlier versions.
+	Namespace string
+
+	Addr     string
+	Username string // ACL username, Redis 6+
+	Password string
+	DB       int
+
+	MasterName       string
+	SentinelAddrs    []string
+	SentinelUsername string
+	SentinelPassword string
+
+	TLS TLSOptions
+
+	PoolSize     int
+	MinIdleConns int
+	PoolTimeout  time.Duration
+	IdleTimeout  time.Duration
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	MaxRetries   int
+}
+
+// TLSOptions enables TLS to Redis, optionally with a private CA and a client
+// certificate for mutual TLS.
+type TLSOptions struct {
+	Enabled            bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+func NewRedisClient(opts Options) (*RedisClient, error) {
+	c, err := newRedisClient(opts)
+	if err != nil {
+		return nil, err
+	}
 
 	// Ping the Redis server to check the connection
-	_, err := client.Ping(client.Context()).Result()
+	if err := c.Ping(context.Background()); err != nil {
+		c.client.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Connect creates a client and pings Redis until it answers or timeout has
+// passed, doubling the wait between attempts up to 30 seconds. When Redis
+// never answers, the client is still returned together with the last error,
+// so callers can start in degraded mode and let the client reconnect later.
+// Configuration errors return a nil client.
+func Connect(opts Options, timeout time.Duration) (*RedisClient, error) {
+	c, err := newRedisClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	backoff := time.Second
+	for {
+		err = c.Ping(context.Background())
+		if err == nil {
+			return c, nil
+		}
+		if !time.Now().Add(backoff).Before(deadline) {
+			return c, err
+		}
+		log.Printf("Redis is not reachable, retrying in %s: %v", backoff, err)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+}
+
+// Ping checks that Redis is reachable.
+func (r *RedisClient) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+func newRedisClient(opts Options) (*RedisClient, error) {
+	tlsConfig, err := opts.TLS.config()
 	if err != nil {
 		return nil, err
 	}
 
-	return &RedisClient{client: client}, nil
+	var client *redis.Client
+	if opts.MasterName != "" {
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       opts.MasterName,
+			SentinelAddrs:    opts.SentinelAddrs,
+			SentinelUsername: opts.SentinelUsername,
+			SentinelPassword: opts.SentinelPassword,
+			Username:         opts.Username,
+			Password:         opts.Password,
+			DB:               opts.DB,
+			MaxRetries:       opts.MaxRetries,
+			DialTimeout:      opts.DialTimeout,
+			ReadTimeout:      opts.ReadTimeout,
+			WriteTimeout:     opts.WriteTimeout,
+			PoolSize:         opts.PoolSize,
+			MinIdleConns:     opts.MinIdleConns,
+			PoolTimeout:      opts.PoolTimeout,
+			IdleTimeout:      opts.IdleTimeout,
+			TLSConfig:        tlsConfig,
+		})
+	} else {
+		client = redis.NewClient(&redis.Options{
+			Addr:         opts.Addr,
+			Username:     opts.Username,
+			Password:     opts.Password,
+			DB:           opts.DB,
+			MaxRetries:   opts.MaxRetries,
+			DialTimeout:  opts.DialTimeout,
+			ReadTimeout:  opts.ReadTimeout,
+			WriteTimeout: opts.WriteTimeout,
+			PoolSize:     opts.PoolSize,
+			MinIdleConns: opts.MinIdleConns,
+			PoolTimeout:  opts.PoolTimeout,
+			IdleTimeout:  opts.IdleTimeout,
+			TLSConfig:    tlsConfig,
+		})
+	}
+
+	return &RedisClient{client: client, prefix: namespacePrefix(opts.Namespace)}, nil
+}
+
+func namespacePrefix(namespace string) string {
+	namespace = strings.TrimRight(strings.TrimSpace(namespace), ":")
+	if namespace == "" {
+		return ""
+	}
+	return namespace + ":"
+}
+
+// Key returns the Redis key for name in the client's namespace.
+func (r *RedisClient) Key(name string) string {
+	return r.prefix + name
+}
+
+// Unkey strips the client's namespace from a Redis key.
+func (r *RedisClient) Unkey(key string) string {
+	return strings.TrimPrefix(key, r.prefix)
+}
+
+// Subspace returns a client sharing r's connection whose keys live under
+// name within r's namespace, e.g. "logvault:trash:".
+func (r *RedisClient) Subspace(name string) *RedisClient {
+	return &RedisClient{client: r.client, prefix: r.prefix + namespacePrefix(name)}
+}
+
+func (o TLSOptions) config() (*tls.Config, error) {
+	if !o.Enabled {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in Redis CA file %s", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Redis client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
 }
 
 func (r *RedisClient) Get(key string) (string, error) {
-	return r.client.Get(r.client.Context(), key).Result()
+	return r.client.Get(r.client.Context(), r.Key(key)).Result()
 }
 
 func (r *RedisClient) Set(key string, value string, expiration time.Duration) error {
-	return r.client.Set(r.client.Context(), key, value, expiration).Err()
+	return r.client.Set(r.client.Context(), r.Key(key), value, expiration).Err()
 }
 
 func (r *RedisClient) Del(keys ...string) error {
-	return r.client.Del(r.client.Context(), keys...).Err()
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		full[i] = r.Key(key)
+	}
+	return r.client.Del(r.client.Context(), full...).Err()
 }
 
 func (r *RedisClient) HGetAll(key string) (map[string]string, error) {
-	return r.client.HGetAll(r.client.Context(), key).Result()
+	return r.client.HGetAll(r.client.Context(), r.Key(key)).Result()
 }
 
 func (r *RedisClient) LPush(key string, values ...interface{}) error {
-	return r.client.LPush(r.client.Context(), key, values...).Err()
+	return r.client.LPush(r.client.Context(), r.Key(key), values...).Err()
 }
 
 func (r *RedisClient) LRange(key string, start, stop int64) ([]string, error) {
-	return r.client.LRange(r.client.Context(), key, start, stop).Result()
+	return r.client.LRange(r.client.Context(), r.Key(key), start, stop).Result()
 }
 
+// scanBatchSize is the COUNT hint passed to SCAN and the maximum number of keys
+// fetched per MGET round trip.
+const scanBatchSize = 1000
+
 func (r *RedisClient) GetAllKeys(ctx context.Context) ([]string, error) {
-	keys, err := r.client.Keys(ctx, "*").Result()
+	keys, err := r.GetKeysByPattern(ctx, "*")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all keys: %w", err)
 	}
 	return keys, nil
 }
 
+// GetKeysByPattern returns the keys in the client's namespace matching
+// pattern, with the namespace stripped.
 func (r *RedisClient) GetKeysByPattern(ctx context.Context, pattern string) ([]string, error) {
-	keys, err := r.client.Keys(ctx, pattern).Result()
+	var keys []string
+	err := r.ScanKeys(ctx, r.Key(pattern), func(batch []string) error {
+		for _, key := range batch {
+			keys = append(keys, r.Unkey(key))
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get keys by pattern %s: %w", pattern, err)
 	}
 	return keys, nil
 }
+
+// ScanKeys walks the keyspace with cursor-based SCAN and hands each batch of
+// matching keys to fn. Unlike KEYS it never blocks Redis for the whole walk.
+// The pattern and keys are full Redis keys; use Key to scope them.
+// A key may be reported more than once if the keyspace is rehashed mid-scan.
+func (r *RedisClient) ScanKeys(ctx context.Context, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// MGet fetches string values for full Redis keys in batches of scanBatchSize.
+// Keys that do not exist or do not hold a string are omitted from the result.
+func (r *RedisClient) MGet(ctx context.Context, keys []string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	for start := 0; start < len(keys); start += scanBatchSize {
+		end := start + scanBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		batch := keys[start:end]
+		result, err := r.client.MGet(ctx, batch...).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range result {
+			if str, ok := v.(string); ok {
+				values[batch[i]] = str
+			}
+		}
+	}
+	return values, nil
+}
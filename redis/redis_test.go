@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTLSOptionsDisabledReturnsNilConfig(t *testing.T) {
+	cfg, err := TLSOptions{}.config()
+	if err != nil || cfg != nil {
+		t.Fatalf("expected no TLS config when disabled, got %v, %v", cfg, err)
+	}
+}
+
+func TestTLSOptionsRejectsCAFileWithoutCertificates(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+
+	if _, err := (TLSOptions{Enabled: true, CAFile: caFile}).config(); err == nil {
+		t.Fatal("expected invalid CA file to be rejected")
+	}
+}
+
+func TestTLSOptionsSetsServerName(t *testing.T) {
+	cfg, err := TLSOptions{Enabled: true, ServerName: "redis.internal"}.config()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ServerName != "redis.internal" {
+		t.Fatalf("expected server name to be set, got %q", cfg.ServerName)
+	}
+}
+
+func TestPreviousStreamID(t *testing.T) {
+	cases := map[string]string{
+		"1700000000000-5": "1700000000000-4",
+		"1700000000000-0": "1699999999999-18446744073709551615",
+		"1700000000000":   "1699999999999-18446744073709551615",
+	}
+	for id, want := range cases {
+		got, err := previousStreamID(id)
+		if err != nil || got != want {
+			t.Fatalf("previousStreamID(%q) = %q, %v; want %q", id, got, err, want)
+		}
+	}
+
+	if _, err := previousStreamID("bogus"); err == nil {
+		t.Fatal("expected invalid stream ID to be rejected")
+	}
+}
+
+func TestKeyAppliesNamespace(t *testing.T) {
+	cases := map[string]string{
+		"":           "alarm:abc",
+		"logvault":   "logvault:alarm:abc",
+		" prod:":     "prod:alarm:abc",
+		"site:east:": "site:east:alarm:abc",
+	}
+	for namespace, want := range cases {
+		c := &RedisClient{prefix: namespacePrefix(namespace)}
+		got := c.Key("alarm:abc")
+		if got != want {
+			t.Fatalf("Key with namespace %q = %q, want %q", namespace, got, want)
+		}
+		if back := c.Unkey(got); back != "alarm:abc" {
+			t.Fatalf("Unkey(%q) = %q, want alarm:abc", got, back)
+		}
+	}
+}
+
+func TestSubspaceNestsWithinNamespace(t *testing.T) {
+	c := &RedisClient{prefix: namespacePrefix("logvault")}
+	if got := c.Subspace("trash").Key("alarm:abc"); got != "logvault:trash:alarm:abc" {
+		t.Fatalf("Subspace key = %q, want logvault:trash:alarm:abc", got)
+	}
+	if got := (&RedisClient{}).Subspace("trash").Key("alarm:abc"); got != "trash:alarm:abc" {
+		t.Fatalf("Subspace key without namespace = %q, want trash:alarm:abc", got)
+	}
+}
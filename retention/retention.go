@@ -0,0 +1,195 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"logvault/config"
+	"logvault/notifier"
+	"logvault/store"
+)
+
+const expiredStatus = "EXPIRED"
+const sweepPageSize = 1000
+
+type rule struct {
+	tag      string
+	severity string
+	maxAge   time.Duration
+}
+
+// Sweeper deletes alarms that are older than the retention configured for
+// their tag or syslog severity. Deletes go through the alarm store, so the
+// time and tag indexes stay consistent with the alarm keys.
+type Sweeper struct {
+	alarms        store.AlarmStore
+	events        store.EventLog
+	appConfig     config.Config
+	rules         []rule
+	defaultMaxAge time.Duration
+	needsSeverity bool
+}
+
+// NewSweeper builds a sweeper from the retention config block. Rules are
+// matched in order and the first match wins; a max_age of 0 keeps matching
+// alarms forever.
+func NewSweeper(alarms store.AlarmStore, events store.EventLog, appConfig config.Config) *Sweeper {
+	s := &Sweeper{
+		alarms:        alarms,
+		events:        events,
+		appConfig:     appConfig,
+		defaultMaxAge: appConfig.Retention.Default,
+	}
+	for _, r := range appConfig.Retention.Rules {
+		compiled := rule{
+			tag:      strings.TrimSpace(r.Tag),
+			severity: strings.ToLower(strings.TrimSpace(r.Severity)),
+			maxAge:   r.MaxAge,
+		}
+		if compiled.tag == "" && compiled.severity == "" {
+			log.Printf("Ignoring retention rule without tag or severity")
+			continue
+		}
+		if compiled.severity != "" {
+			s.needsSeverity = true
+		}
+		s.rules = append(s.rules, compiled)
+	}
+	return s
+}
+
+// Run sweeps expired alarms every interval. It returns immediately when no
+// retention is configured.
+func (s *Sweeper) Run(interval time.Duration) {
+	if s.shortestMaxAge() == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		expired, err := s.Sweep(context.Background(), time.Now())
+		if err != nil {
+			log.Printf("Retention sweep failed: %v", err)
+		}
+		if len(expired) > 0 {
+			s.report(expired)
+		}
+	}
+}
+
+// Sweep deletes every alarm whose age at now exceeds its retention and
+// returns the number of expired alarms per tag.
+func (s *Sweeper) Sweep(ctx context.Context, now time.Time) (map[string]int, error) {
+	expired := make(map[string]int)
+	shortest := s.shortestMaxAge()
+	if shortest == 0 {
+		return expired, nil
+	}
+
+	// Only alarms older than the shortest retention can have expired.
+	q := store.Query{Until: now.Add(-shortest), Limit: sweepPageSize}
+	for {
+		page, err := s.alarms.Query(ctx, q)
+		if err != nil {
+			return expired, err
+		}
+
+		var ids []string
+		var history []store.Event
+		for _, rec := range page.Records {
+			maxAge := s.maxAgeFor(rec)
+			if maxAge > 0 && now.Sub(rec.ReceivedAt) > maxAge {
+				ids = append(ids, rec.ID)
+				history = append(history, store.Event{
+					Type:    store.EventExpire,
+					AlarmID: rec.ID,
+					Tag:     rec.Tag,
+					Actor:   "retention",
+					Message: fmt.Sprintf("Expired after %s", maxAge),
+					Data:    rec.Value,
+				})
+			}
+		}
+		if len(ids) > 0 {
+			if _, err := s.alarms.Delete(ctx, ids...); err != nil {
+				return expired, err
+			}
+			for _, e := range history {
+				expired[strings.ToUpper(e.Tag)]++
+			}
+			if s.events != nil {
+				if err := s.events.Append(ctx, history...); err != nil {
+					log.Printf("Failed to record expire events: %v", err)
+				}
+			}
+		}
+
+		if page.NextCursor == "" {
+			return expired, nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func (s *Sweeper) maxAgeFor(rec store.Record) time.Duration {
+	severity := ""
+	if s.needsSeverity {
+		var doc struct {
+			Severity string `json:"severity"`
+		}
+		if err := json.Unmarshal([]byte(rec.Value), &doc); err == nil {
+			severity = strings.ToLower(doc.Severity)
+		}
+	}
+
+	for _, r := range s.rules {
+		if r.tag != "" && !strings.EqualFold(r.tag, rec.Tag) {
+			continue
+		}
+		if r.severity != "" && r.severity != severity {
+			continue
+		}
+		return r.maxAge
+	}
+	return s.defaultMaxAge
+}
+
+func (s *Sweeper) shortestMaxAge() time.Duration {
+	shortest := s.defaultMaxAge
+	for _, r := range s.rules {
+		if r.maxAge > 0 && (shortest == 0 || r.maxAge < shortest) {
+			shortest = r.maxAge
+		}
+	}
+	return shortest
+}
+
+func (s *Sweeper) report(expired map[string]int) {
+	tags := make([]string, 0, len(expired))
+	total := 0
+	for tag, n := range expired {
+		tags = append(tags, fmt.Sprintf("%s=%d", tag, n))
+		total += n
+	}
+	sort.Strings(tags)
+
+	summary := fmt.Sprintf("Expired %d alarms by retention policy (%s)", total, strings.Join(tags, ", "))
+	log.Printf("RETENTION: %s", summary)
+
+	if s.appConfig.Retention.Notify && s.appConfig.ExternalAPI.Enabled {
+		go notifier.CallExternalAPI(s.appConfig, map[string]string{
+			"key":     "RETENTION",
+			"message": summary,
+			"status":  expiredStatus,
+		})
+	}
+}
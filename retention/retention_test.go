@@ -0,0 +1,79 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"logvault/config"
+	"logvault/store"
+)
+
+func newTestConfig() config.Config {
+	cfg := config.Config{}
+	cfg.Retention.Rules = []struct {
+		Tag      string        `mapstructure:"tag"`
+		Severity string        `mapstructure:"severity"`
+		MaxAge   time.Duration `mapstructure:"max_age"`
+	}{
+		{Tag: "INSIGHTS", MaxAge: 90 * 24 * time.Hour},
+		{Severity: "info", MaxAge: 24 * time.Hour},
+	}
+	return cfg
+}
+
+func TestSweepDeletesAlarmsPastTheirRetention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	alarms := store.NewMemoryStore()
+
+	put := func(id, tag, severity string, age time.Duration) {
+		alarms.Put(ctx, store.Record{
+			ID:         id,
+			Tag:        tag,
+			ReceivedAt: now.Add(-age),
+			Value:      `{"tag":"` + tag + `","severity":"` + severity + `"}`,
+		})
+	}
+	put("old-info", "ALARM", "info", 48*time.Hour)
+	put("new-info", "ALARM", "info", time.Hour)
+	put("old-err", "ALARM", "err", 48*time.Hour)
+	put("recent-insight", "INSIGHTS", "info", 30*24*time.Hour)
+	put("old-insight", "INSIGHTS", "info", 100*24*time.Hour)
+
+	events := store.NewMemoryEventLog(0)
+	expired, err := NewSweeper(alarms, events, newTestConfig()).Sweep(ctx, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expired["ALARM"] != 1 || expired["INSIGHTS"] != 1 {
+		t.Fatalf("unexpected expiry counts: %v", expired)
+	}
+
+	for _, id := range []string{"new-info", "old-err", "recent-insight"} {
+		if _, err := alarms.Get(ctx, id); err != nil {
+			t.Fatalf("expected %s to be kept, got %v", id, err)
+		}
+	}
+	for _, id := range []string{"old-info", "old-insight"} {
+		if _, err := alarms.Get(ctx, id); err == nil {
+			t.Fatalf("expected %s to be expired", id)
+		}
+	}
+
+	history, _ := events.Events(ctx, store.EventQuery{})
+	if len(history.Events) != 2 || history.Events[0].Type != store.EventExpire {
+		t.Fatalf("expected two expire events, got %+v", history.Events)
+	}
+}
+
+func TestSweepKeepsEverythingWithoutRetention(t *testing.T) {
+	ctx := context.Background()
+	alarms := store.NewMemoryStore()
+	alarms.Put(ctx, store.Record{ID: "a", Tag: "ALARM", ReceivedAt: time.Unix(0, 0)})
+
+	expired, err := NewSweeper(alarms, nil, config.Config{}).Sweep(ctx, time.Now())
+	if err != nil || len(expired) != 0 {
+		t.Fatalf("expected nothing to expire, got %v, %v", expired, err)
+	}
+}
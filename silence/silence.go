@@ -0,0 +1,330 @@
+package silence
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"logvault/alarm"
+	"logvault/maintenance"
+	"logvault/store"
+)
+
+// Silence statuses relative to the current time.
+const (
+	StatusPending = "pending"
+	StatusActive  = "active"
+	StatusExpired = "expired"
+)
+
+// ErrInvalid is returned for silences without matchers or with an empty or
+// unparseable time range.
+var ErrInvalid = errors.New("invalid silence")
+
+// Matchers select the alarms a silence applies to. Every non-empty matcher
+// must match. Tag and RuleName compare case-insensitively; IP, compared with
+// the alarm's IP field, and Sender, compared with the sender's IP or
+// hostname, also accept CIDR ranges.
+type Matchers struct {
+	Tag      string `json:"tag,omitempty"`
+	IP       string `json:"ip,omitempty"`
+	RuleName string `json:"rule_name,omitempty"`
+	Sender   string `json:"sender,omitempty"`
+}
+
+// Silence suppresses external API notifications for matching alarms between
+// StartsAt and EndsAt. Matching alarms are still stored.
+type Silence struct {
+	ID        string    `json:"id"`
+	Matchers  Matchers  `json:"matchers"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Comment   string    `json:"comment,omitempty"`
+}
+
+// Status reports whether the silence is pending, active or expired at now.
+func (s Silence) Status(now time.Time) string {
+	switch {
+	case now.Before(s.StartsAt):
+		return StatusPending
+	case now.Before(s.EndsAt):
+		return StatusActive
+	default:
+		return StatusExpired
+	}
+}
+
+// Matches reports whether the silence applies to doc, ignoring its time range.
+func (s Silence) Matches(doc alarm.Document) bool {
+	m := s.Matchers
+	if m.Tag != "" && !strings.EqualFold(m.Tag, doc.Tag) {
+		return false
+	}
+	if m.RuleName != "" && !strings.EqualFold(m.RuleName, doc.Fields["RuleName"]) {
+		return false
+	}
+	if m.IP != "" && !matchesAddress(m.IP, doc.Fields["IP"]) {
+		return false
+	}
+	if m.Sender != "" && !matchesAddress(m.Sender, doc.Source.IP) && !strings.EqualFold(m.Sender, doc.Source.Hostname) {
+		return false
+	}
+	return true
+}
+
+// matchesAddress compares an address with pattern, an IP, CIDR range or
+// other exact value such as a hostname.
+func matchesAddress(pattern, value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false
+	}
+	if _, network, err := net.ParseCIDR(pattern); err == nil {
+		ip := net.ParseIP(value)
+		return ip != nil && network.Contains(ip)
+	}
+	return strings.EqualFold(pattern, value)
+}
+
+func (s *Silence) validate() error {
+	s.Matchers.Tag = strings.TrimSpace(s.Matchers.Tag)
+	s.Matchers.IP = strings.TrimSpace(s.Matchers.IP)
+	s.Matchers.RuleName = strings.TrimSpace(s.Matchers.RuleName)
+	s.Matchers.Sender = strings.TrimSpace(s.Matchers.Sender)
+	s.Comment = strings.TrimSpace(s.Comment)
+
+	if s.Matchers == (Matchers{}) {
+		return fmt.Errorf("%w: at least one matcher is required", ErrInvalid)
+	}
+	for _, addr := range []string{s.Matchers.IP, s.Matchers.Sender} {
+		if strings.Contains(addr, "/") {
+			if _, _, err := net.ParseCIDR(addr); err != nil {
+				return fmt.Errorf("%w: %q is not a valid CIDR range", ErrInvalid, addr)
+			}
+		}
+	}
+	if s.EndsAt.IsZero() || !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalid)
+	}
+	return nil
+}
+
+// Silencer keeps silences in a store and answers which one, if any, applies
+// to an alarm. Silences are cached in memory, so matching does not touch the
+// store; Run reloads them so changes made by other instances are picked up.
+// Recurring maintenance windows suppress notifications the same way. A nil
+// Silencer matches nothing.
+type Silencer struct {
+	store     store.AlarmStore
+	retention time.Duration
+	windows   *maintenance.Calendar
+
+	mu       sync.RWMutex
+	silences map[string]Silence
+}
+
+// New returns a silencer backed by st that also applies the maintenance
+// windows, which may be nil. Expired silences are kept for retention before
+// Run removes them; zero keeps them until deleted.
+func New(st store.AlarmStore, retention time.Duration, windows *maintenance.Calendar) *Silencer {
+	return &Silencer{store: st, retention: retention, windows: windows, silences: make(map[string]Silence)}
+}
+
+// Calendar returns the maintenance windows.
+func (s *Silencer) Calendar() *maintenance.Calendar {
+	if s == nil {
+		return nil
+	}
+	return s.windows
+}
+
+// Load replaces the cached silences with those in the store.
+func (s *Silencer) Load(ctx context.Context) error {
+	recs, err := s.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	silences := make(map[string]Silence, len(recs))
+	for _, rec := range recs {
+		var sil Silence
+		if err := json.Unmarshal([]byte(rec.Value), &sil); err != nil {
+			log.Printf("Skipping invalid silence %s: %v", rec.ID, err)
+			continue
+		}
+		silences[sil.ID] = sil
+	}
+
+	s.mu.Lock()
+	s.silences = silences
+	s.mu.Unlock()
+	return nil
+}
+
+// Create validates and stores a new silence. A zero StartsAt starts it now.
+func (s *Silencer) Create(ctx context.Context, sil Silence) (Silence, error) {
+	now := time.Now()
+	if sil.StartsAt.IsZero() {
+		sil.StartsAt = now
+	}
+	if err := sil.validate(); err != nil {
+		return sil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return sil, err
+	}
+	sil.ID = hex.EncodeToString(id)
+	sil.CreatedAt = now
+
+	value, err := json.Marshal(sil)
+	if err != nil {
+		return sil, err
+	}
+	if err := s.store.Put(ctx, store.Record{ID: sil.ID, ReceivedAt: sil.CreatedAt, Value: string(value)}); err != nil {
+		return sil, err
+	}
+
+	s.mu.Lock()
+	s.silences[sil.ID] = sil
+	s.mu.Unlock()
+	return sil, nil
+}
+
+// Delete removes a silence and reports whether it existed.
+func (s *Silencer) Delete(ctx context.Context, id string) (bool, error) {
+	n, err := s.store.Delete(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	delete(s.silences, id)
+	s.mu.Unlock()
+	return n > 0, nil
+}
+
+// List returns the cached silences, newest first.
+func (s *Silencer) List() []Silence {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	list := make([]Silence, 0, len(s.silences))
+	for _, sil := range s.silences {
+		list = append(list, sil)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.After(list[j].CreatedAt)
+		}
+		return list[i].ID > list[j].ID
+	})
+	return list
+}
+
+// Match returns the silence active at at that applies to doc, or nil. When
+// several apply, the one ending last is returned.
+func (s *Silencer) Match(doc alarm.Document, at time.Time) *Silence {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var match *Silence
+	for _, sil := range s.silences {
+		if sil.Status(at) != StatusActive || !sil.Matches(doc) {
+			continue
+		}
+		if match == nil || sil.EndsAt.After(match.EndsAt) {
+			sil := sil
+			match = &sil
+		}
+	}
+	return match
+}
+
+// Suppress flags doc with the silence and the maintenance window, if any,
+// active when it was received, and reports whether its notification is
+// suppressed.
+func (s *Silencer) Suppress(doc *alarm.Document) bool {
+	if s == nil {
+		return false
+	}
+	if sil := s.Match(*doc, doc.ReceivedAt); sil != nil {
+		doc.SilencedBy = sil.ID
+	}
+	if o := s.windows.Active(*doc, doc.ReceivedAt); o != nil {
+		doc.Maintenance = o.Window
+	}
+	return doc.Suppressed()
+}
+
+// PurgeExpired removes silences that ended before cutoff and returns how
+// many were removed.
+func (s *Silencer) PurgeExpired(ctx context.Context, cutoff time.Time) (int, error) {
+	var ids []string
+	for _, sil := range s.List() {
+		if sil.EndsAt.Before(cutoff) {
+			ids = append(ids, sil.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	n, err := s.store.Delete(ctx, ids...)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	for _, id := range ids {
+		delete(s.silences, id)
+	}
+	s.mu.Unlock()
+	return n, nil
+}
+
+// Run reloads silences from the store every interval and removes those that
+// expired more than the retention ago.
+func (s *Silencer) Run(interval time.Duration) {
+	if s == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx := context.Background()
+		if err := s.Load(ctx); err != nil {
+			log.Printf("Failed to reload silences: %v", err)
+			continue
+		}
+		if s.retention <= 0 {
+			continue
+		}
+		purged, err := s.PurgeExpired(ctx, time.Now().Add(-s.retention))
+		if err != nil {
+			log.Printf("Failed to purge expired silences: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("SILENCE: Removed %d silences that expired more than %s ago", purged, s.retention)
+		}
+	}
+}
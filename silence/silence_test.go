@@ -0,0 +1,117 @@
+package silence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"logvault/alarm"
+	"logvault/maintenance"
+	"logvault/store"
+)
+
+func threatAlarm(ip, rule, sender string) alarm.Document {
+	doc := alarm.New("a1", "INSIGHTS", time.Now())
+	doc.Source.IP = sender
+	doc.Fields = map[string]string{"IP": ip, "RuleName": rule}
+	return doc
+}
+
+func TestSilenceMatchesEveryMatcher(t *testing.T) {
+	s := Silence{Matchers: Matchers{Tag: "insights", IP: "10.0.0.0/8", RuleName: "Port Scan"}}
+
+	if !s.Matches(threatAlarm("10.1.2.3", "port scan", "192.0.2.1")) {
+		t.Fatal("expected tag, CIDR and rule name to match")
+	}
+	if s.Matches(threatAlarm("192.168.1.1", "Port Scan", "192.0.2.1")) {
+		t.Fatal("expected an IP outside the range not to match")
+	}
+	if s.Matches(threatAlarm("10.1.2.3", "Malware", "192.0.2.1")) {
+		t.Fatal("expected a different rule not to match")
+	}
+
+	bySender := Silence{Matchers: Matchers{Sender: "192.0.2.1"}}
+	if !bySender.Matches(threatAlarm("", "", "192.0.2.1")) || bySender.Matches(threatAlarm("", "", "192.0.2.2")) {
+		t.Fatal("expected the sender matcher to compare the sender IP")
+	}
+}
+
+func TestCreateValidatesAndMatchOnlyWhileActive(t *testing.T) {
+	ctx := context.Background()
+	s := New(store.NewMemoryStore(), 0, nil)
+	now := time.Now()
+
+	if _, err := s.Create(ctx, Silence{EndsAt: now.Add(time.Hour)}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected a silence without matchers to be rejected, got %v", err)
+	}
+	if _, err := s.Create(ctx, Silence{Matchers: Matchers{Tag: "INSIGHTS"}}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected a silence without an end to be rejected, got %v", err)
+	}
+	if _, err := s.Create(ctx, Silence{Matchers: Matchers{IP: "10.0.0.0/33"}, EndsAt: now.Add(time.Hour)}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected an invalid CIDR to be rejected, got %v", err)
+	}
+
+	created, err := s.Create(ctx, Silence{Matchers: Matchers{Tag: "INSIGHTS"}, StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour), CreatedBy: "admin"})
+	if err != nil || created.ID == "" {
+		t.Fatalf("expected silence to be created, got %+v, %v", created, err)
+	}
+
+	doc := threatAlarm("10.1.2.3", "Port Scan", "192.0.2.1")
+	if s.Match(doc, now) != nil {
+		t.Fatal("expected a pending silence not to match")
+	}
+	if m := s.Match(doc, now.Add(90*time.Minute)); m == nil || m.ID != created.ID {
+		t.Fatalf("expected the active silence to match, got %+v", m)
+	}
+	if s.Match(doc, now.Add(3*time.Hour)) != nil {
+		t.Fatal("expected an expired silence not to match")
+	}
+	var nilSilencer *Silencer
+	if nilSilencer.Match(doc, now) != nil {
+		t.Fatal("expected a nil silencer to match nothing")
+	}
+}
+
+func TestLoadAndPurgeExpired(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	now := time.Now()
+	first := New(st, 0, nil)
+	first.Create(ctx, Silence{Matchers: Matchers{Tag: "OLD"}, StartsAt: now.Add(-3 * time.Hour), EndsAt: now.Add(-2 * time.Hour)})
+	first.Create(ctx, Silence{Matchers: Matchers{Tag: "NEW"}, EndsAt: now.Add(time.Hour)})
+
+	// Another instance picks the silences up from the shared store.
+	second := New(st, 0, nil)
+	if err := second.Load(ctx); err != nil || len(second.List()) != 2 {
+		t.Fatalf("expected two silences after load, got %d, %v", len(second.List()), err)
+	}
+
+	purged, err := second.PurgeExpired(ctx, now.Add(-time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("expected one expired silence purged, got %d, %v", purged, err)
+	}
+	if list := second.List(); len(list) != 1 || list[0].Matchers.Tag != "NEW" {
+		t.Fatalf("expected only the current silence to remain, got %+v", list)
+	}
+}
+
+func TestSuppressFlagsMaintenanceWindows(t *testing.T) {
+	windows, err := maintenance.New([]maintenance.WindowConfig{{Name: "nightly", Schedule: "0 1 * * *", Duration: 2 * time.Hour, Timezone: "UTC", Tags: []string{"INSIGHTS"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := New(store.NewMemoryStore(), 0, windows)
+
+	doc := threatAlarm("10.1.2.3", "Port Scan", "192.0.2.1")
+	doc.ReceivedAt = time.Date(2026, 3, 4, 1, 30, 0, 0, time.UTC)
+	if !s.Suppress(&doc) || doc.Maintenance != "nightly" || doc.SilencedBy != "" {
+		t.Fatalf("expected the alarm to be flagged with the maintenance window, got %+v", doc)
+	}
+
+	later := threatAlarm("10.1.2.3", "Port Scan", "192.0.2.1")
+	later.ReceivedAt = time.Date(2026, 3, 4, 3, 30, 0, 0, time.UTC)
+	if s.Suppress(&later) || later.Suppressed() {
+		t.Fatalf("expected an alarm outside the window not to be suppressed, got %+v", later)
+	}
+}
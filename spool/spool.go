@@ -0,0 +1,304 @@
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"logvault/store"
+)
+
+const fileName = "spool.ndjson"
+
+// Status describes whether writes are currently going to the spool.
+type Status struct {
+	Degraded      bool      `json:"degraded"`
+	DegradedSince time.Time `json:"degraded_since,omitempty"`
+	Pending       int       `json:"pending"`
+}
+
+// entry is one spooled write. Exactly one of Alarm and Event is set.
+type entry struct {
+	Alarm *alarmEntry  `json:"alarm,omitempty"`
+	Event *store.Event `json:"event,omitempty"`
+}
+
+type alarmEntry struct {
+	ID         string    `json:"id"`
+	Tag        string    `json:"tag"`
+	ReceivedAt time.Time `json:"received_at"`
+	Value      string    `json:"value"`
+}
+
+// Spool keeps alarm and event writes on local disk while the backing store is
+// unreachable and replays them, in order, once it answers again. While
+// degraded, new writes go straight to the spool so syslog processing is not
+// held up by connection timeouts.
+type Spool struct {
+	path   string
+	alarms store.AlarmStore
+	events store.EventLog
+	ping   func(context.Context) error
+
+	mu       sync.Mutex
+	degraded bool
+	since    time.Time
+	pending  int
+}
+
+// Open prepares the spool in dir. Writes left over from a previous run are
+// counted and replayed by Run.
+func Open(dir string, alarms store.AlarmStore, events store.EventLog, ping func(context.Context) error) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	s := &Spool{path: filepath.Join(dir, fileName), alarms: alarms, events: events, ping: ping}
+	lines, err := s.readLines()
+	if err != nil {
+		return nil, err
+	}
+	s.pending = len(lines)
+	if s.pending > 0 {
+		log.Printf("SPOOL: %d writes from a previous run are waiting to be replayed", s.pending)
+	}
+	return s, nil
+}
+
+// AlarmStore returns a store that writes alarms through the spool.
+func (s *Spool) AlarmStore() store.AlarmStore {
+	return spooledAlarms{AlarmStore: s.alarms, spool: s}
+}
+
+// EventLog returns an event log that appends events through the spool.
+func (s *Spool) EventLog() store.EventLog {
+	return spooledEvents{EventLog: s.events, spool: s}
+}
+
+// Status reports the current spool state. A nil *Spool reports healthy.
+func (s *Spool) Status() Status {
+	if s == nil {
+		return Status{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Status{Degraded: s.degraded, DegradedSince: s.since, Pending: s.pending}
+}
+
+// Run checks the store every interval, entering degraded mode when it stops
+// answering and replaying spooled writes when it is back. It never returns.
+func (s *Spool) Run(interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := s.Replay(context.Background()); err != nil {
+			log.Printf("SPOOL: Replay stopped: %v", err)
+		}
+	}
+}
+
+// Replay checks the store and, if it answers, writes spooled entries back in
+// order. Entries that fail stay in the spool for the next attempt.
+func (s *Spool) Replay(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ping(ctx); err != nil {
+		s.markDegradedLocked(err)
+		if s.pending > 0 {
+			return 0, err
+		}
+		return 0, nil
+	}
+	if s.pending == 0 {
+		s.markHealthyLocked()
+		return 0, nil
+	}
+
+	lines, err := s.readLines()
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for i, line := range lines {
+		if err := s.apply(ctx, line); err != nil {
+			if rerr := s.rewriteLocked(lines[i:]); rerr != nil {
+				return replayed, rerr
+			}
+			s.markDegradedLocked(err)
+			return replayed, err
+		}
+		replayed++
+	}
+
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return replayed, err
+	}
+	s.pending = 0
+	s.markHealthyLocked()
+	log.Printf("SPOOL: Replayed %d spooled writes", replayed)
+	return replayed, nil
+}
+
+func (s *Spool) apply(ctx context.Context, line []byte) error {
+	var e entry
+	if err := json.Unmarshal(line, &e); err != nil {
+		log.Printf("SPOOL: Skipping unreadable entry: %v", err)
+		return nil
+	}
+	switch {
+	case e.Alarm != nil:
+		return s.alarms.Put(ctx, store.Record{ID: e.Alarm.ID, Tag: e.Alarm.Tag, ReceivedAt: e.Alarm.ReceivedAt, Value: e.Alarm.Value})
+	case e.Event != nil:
+		return s.events.Append(ctx, *e.Event)
+	}
+	return nil
+}
+
+// write sends a write to the store unless the spool is degraded. When the
+// store fails and does not answer a ping either, the entries are spooled
+// instead.
+func (s *Spool) write(ctx context.Context, entries []entry, direct func() error) error {
+	s.mu.Lock()
+	degraded := s.degraded
+	s.mu.Unlock()
+
+	if !degraded {
+		err := direct()
+		if err == nil {
+			return nil
+		}
+		if s.ping(ctx) == nil {
+			return err // The store is up; this write failed for another reason
+		}
+		s.mu.Lock()
+		s.markDegradedLocked(err)
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appendLocked(entries)
+}
+
+func (s *Spool) appendLocked(entries []entry) error {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open spool: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write spool: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spool: %w", err)
+	}
+	s.pending += len(entries)
+	return nil
+}
+
+func (s *Spool) markDegradedLocked(err error) {
+	if s.degraded {
+		return
+	}
+	s.degraded = true
+	s.since = time.Now()
+	log.Printf("SPOOL: Storage is unavailable, spooling writes to %s: %v", s.path, err)
+}
+
+func (s *Spool) markHealthyLocked() {
+	if !s.degraded {
+		return
+	}
+	s.degraded = false
+	s.since = time.Time{}
+	log.Printf("SPOOL: Storage is available again")
+}
+
+func (s *Spool) readLines() ([][]byte, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool: %w", err)
+	}
+
+	var lines [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+	}
+	return lines, scanner.Err()
+}
+
+// rewriteLocked replaces the spool with the given lines.
+func (s *Spool) rewriteLocked(lines [][]byte) error {
+	tmp := s.path + ".tmp"
+	data := bytes.Join(lines, []byte("\n"))
+	data = append(data, '\n')
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("failed to rewrite spool: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to rewrite spool: %w", err)
+	}
+	s.pending = len(lines)
+	return nil
+}
+
+type spooledAlarms struct {
+	store.AlarmStore
+	spool *Spool
+}
+
+func (a spooledAlarms) Put(ctx context.Context, rec store.Record) error {
+	e := entry{Alarm: &alarmEntry{ID: rec.ID, Tag: rec.Tag, ReceivedAt: rec.ReceivedAt, Value: rec.Value}}
+	return a.spool.write(ctx, []entry{e}, func() error { return a.AlarmStore.Put(ctx, rec) })
+}
+
+type spooledEvents struct {
+	store.EventLog
+	spool *Spool
+}
+
+// Append writes events through the spool. Spooled events get their ID and
+// time from the event log when they are replayed.
+func (l spooledEvents) Append(ctx context.Context, events ...store.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	entries := make([]entry, len(events))
+	for i := range events {
+		entries[i] = entry{Event: &events[i]}
+	}
+	return l.spool.write(ctx, entries, func() error { return l.EventLog.Append(ctx, events...) })
+}
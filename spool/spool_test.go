@@ -0,0 +1,147 @@
+package spool
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"logvault/store"
+)
+
+var errDown = errors.New("connection refused")
+
+// flakyStore fails writes while down is set.
+type flakyStore struct {
+	*store.MemoryStore
+	down *bool
+}
+
+func (f flakyStore) Put(ctx context.Context, rec store.Record) error {
+	if *f.down {
+		return errDown
+	}
+	return f.MemoryStore.Put(ctx, rec)
+}
+
+type flakyEvents struct {
+	*store.MemoryEventLog
+	down *bool
+}
+
+func (f flakyEvents) Append(ctx context.Context, events ...store.Event) error {
+	if *f.down {
+		return errDown
+	}
+	return f.MemoryEventLog.Append(ctx, events...)
+}
+
+func newTestSpool(t *testing.T) (*Spool, *store.MemoryStore, *store.MemoryEventLog, *bool) {
+	t.Helper()
+
+	down := new(bool)
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	ping := func(context.Context) error {
+		if *down {
+			return errDown
+		}
+		return nil
+	}
+
+	s, err := Open(t.TempDir(), flakyStore{alarms, down}, flakyEvents{events, down}, ping)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	return s, alarms, events, down
+}
+
+func TestSpoolBuffersWritesWhileStoreIsDown(t *testing.T) {
+	s, alarms, events, down := newTestSpool(t)
+	ctx := context.Background()
+
+	*down = true
+	rec := store.Record{ID: "a1", Tag: "ALARM", ReceivedAt: time.Now(), Value: `{"tag":"ALARM"}`}
+	if err := s.AlarmStore().Put(ctx, rec); err != nil {
+		t.Fatalf("expected spooled Put to succeed, got %v", err)
+	}
+	if err := s.EventLog().Append(ctx, store.Event{Type: store.EventIngest, AlarmID: "a1"}, store.Event{Type: store.EventDelete, AlarmID: "a1"}); err != nil {
+		t.Fatalf("expected spooled Append to succeed, got %v", err)
+	}
+
+	st := s.Status()
+	if !st.Degraded || st.Pending != 3 {
+		t.Fatalf("expected degraded status with 3 pending writes, got %+v", st)
+	}
+
+	if _, err := s.Replay(ctx); !errors.Is(err, errDown) {
+		t.Fatalf("expected replay to fail while store is down, got %v", err)
+	}
+
+	*down = false
+	replayed, err := s.Replay(ctx)
+	if err != nil || replayed != 3 {
+		t.Fatalf("expected 3 writes replayed, got %d, %v", replayed, err)
+	}
+	if st := s.Status(); st.Degraded || st.Pending != 0 {
+		t.Fatalf("expected healthy status after replay, got %+v", st)
+	}
+
+	got, err := alarms.Get(ctx, "a1")
+	if err != nil || got.Value != rec.Value || !got.ReceivedAt.Equal(rec.ReceivedAt) {
+		t.Fatalf("expected replayed alarm to match original, got %+v, %v", got, err)
+	}
+	page, _ := events.Events(ctx, store.EventQuery{})
+	if len(page.Events) != 2 {
+		t.Fatalf("expected 2 replayed events, got %d", len(page.Events))
+	}
+}
+
+func TestSpoolSurvivesRestart(t *testing.T) {
+	s, _, _, down := newTestSpool(t)
+	ctx := context.Background()
+
+	*down = true
+	if err := s.AlarmStore().Put(ctx, store.Record{ID: "a1", ReceivedAt: time.Now(), Value: "{}"}); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	alarms := store.NewMemoryStore()
+	reopened, err := Open(filepath.Dir(s.path), alarms, store.NewMemoryEventLog(0), func(context.Context) error { return nil })
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if st := reopened.Status(); st.Pending != 1 {
+		t.Fatalf("expected 1 pending write after reopen, got %+v", st)
+	}
+	if _, err := reopened.Replay(ctx); err != nil {
+		t.Fatalf("Replay returned error: %v", err)
+	}
+	if _, err := alarms.Get(ctx, "a1"); err != nil {
+		t.Fatalf("expected alarm to be replayed after restart, got %v", err)
+	}
+}
+
+func TestSpoolReturnsErrorsWhenStoreIsUp(t *testing.T) {
+	s, _, _, _ := newTestSpool(t)
+	wantErr := errors.New("WRONGTYPE")
+	s.alarms = failingStore{store.NewMemoryStore(), wantErr}
+
+	err := s.AlarmStore().Put(context.Background(), store.Record{ID: "a1"})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("expected store error to be returned, got %v", err)
+	}
+	if st := s.Status(); st.Degraded || st.Pending != 0 {
+		t.Fatalf("expected nothing spooled, got %+v", st)
+	}
+}
+
+type failingStore struct {
+	*store.MemoryStore
+	err error
+}
+
+func (f failingStore) Put(ctx context.Context, rec store.Record) error {
+	return f.err
+}
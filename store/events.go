@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Event types recorded in the alarm history.
+const (
+	EventIngest   = "ingest"
+	EventDelete   = "delete"
+	EventExpire   = "expire"
+	EventAck      = "ack"
+	EventUnack    = "unack"
+	EventResolve  = "resolve"
+	EventClose    = "close"
+	EventReopen   = "reopen"
+	EventRestore  = "restore"
+	EventComment  = "comment"
+	EventAssign   = "assign"
+	EventEscalate = "escalate"
+	EventLabel    = "label"
+)
+
+// Event is one entry of the append-only alarm history. Data holds the alarm
+// document at the time of the event so history survives deletes. OccurredAt
+// is set on events restored from a backup and holds their original time,
+// while Time is when they were appended again.
+type Event struct {
+	ID         string     `json:"id"`
+	Time       time.Time  `json:"time"`
+	OccurredAt *time.Time `json:"occurred_at,omitempty"`
+	Type       string     `json:"type"`
+	AlarmID    string     `json:"alarm_id"`
+	Tag        string     `json:"tag,omitempty"`
+	Actor      string     `json:"actor,omitempty"`
+	Message    string     `json:"message,omitempty"`
+	Data       string     `json:"data,omitempty"`
+}
+
+// EventLog is an append-only, size-capped history of what happened to alarms.
+// It is independent of the current alarm state.
+type EventLog interface {
+	// Append records events in order. IDs and times are assigned by the log.
+	Append(ctx context.Context, events ...Event) error
+	// Events returns events matching q, newest first.
+	Events(ctx context.Context, q EventQuery) (EventPage, error)
+}
+
+// EventQuery selects history entries. Before is the NextCursor of a previous
+// page; a zero Limit returns every match.
+type EventQuery struct {
+	AlarmID string
+	Since   time.Time
+	Until   time.Time
+	Before  string
+	Limit   int
+}
+
+type EventPage struct {
+	Events     []Event
+	NextCursor string
+}
+
+// Matches reports whether an event satisfies the alarm and time filters.
+func (q EventQuery) Matches(e Event) bool {
+	if q.AlarmID != "" && e.AlarmID != q.AlarmID {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && e.Time.After(q.Until) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// MemoryStore keeps alarms in process memory. Data is lost on restart, so it
+// is meant for development, labs without Redis, and tests.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	alarms map[string]Record
+	// fingerprints maps each fingerprint to the IDs of the alarms having it.
+	fingerprints map[string]map[string]struct{}
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{alarms: make(map[string]Record), fingerprints: make(map[string]map[string]struct{})}
+}
+
+func (m *MemoryStore) Put(ctx context.Context, rec Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.putLocked(rec)
+	return nil
+}
+
+func (m *MemoryStore) Swap(ctx context.Context, old, rec Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.alarms[old.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if cur.Value != old.Value {
+		return ErrConflict
+	}
+	m.putLocked(rec)
+	return nil
+}
+
+func (m *MemoryStore) putLocked(rec Record) {
+	if prev, ok := m.alarms[rec.ID]; ok {
+		m.unindexLocked(prev)
+	}
+	m.alarms[rec.ID] = rec
+	if fp := FingerprintOf(rec.Value); fp != "" {
+		if m.fingerprints[fp] == nil {
+			m.fingerprints[fp] = make(map[string]struct{})
+		}
+		m.fingerprints[fp][rec.ID] = struct{}{}
+	}
+}
+
+func (m *MemoryStore) unindexLocked(rec Record) {
+	fp := FingerprintOf(rec.Value)
+	if ids := m.fingerprints[fp]; ids != nil {
+		delete(ids, rec.ID)
+		if len(ids) == 0 {
+			delete(m.fingerprints, fp)
+		}
+	}
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (Record, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rec, ok := m.alarms[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	return rec, nil
+}
+
+func (m *MemoryStore) List(ctx context.Context) ([]Record, error) {
+	page, err := m.Query(ctx, Query{})
+	return page.Records, err
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, ids ...string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := 0
+	for _, id := range ids {
+		if rec, ok := m.alarms[id]; ok {
+			m.unindexLocked(rec)
+			delete(m.alarms, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *MemoryStore) Query(ctx context.Context, q Query) (Page, error) {
+	var cursor *Cursor
+	if q.Cursor != "" {
+		c, err := ParseCursor(q.Cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		cursor = &c
+	}
+
+	m.mu.RLock()
+	candidates := m.alarms
+	if q.Fingerprint != "" {
+		candidates = make(map[string]Record, len(m.fingerprints[q.Fingerprint]))
+		for id := range m.fingerprints[q.Fingerprint] {
+			candidates[id] = m.alarms[id]
+		}
+	}
+	matches := make([]Record, 0, len(candidates))
+	for _, rec := range candidates {
+		if cursor != nil && !cursor.Includes(rec.ReceivedAt.UnixMilli(), rec.ID) {
+			continue
+		}
+		if q.Matches(rec) {
+			matches = append(matches, rec)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		ti, tj := matches[i].ReceivedAt.UnixMilli(), matches[j].ReceivedAt.UnixMilli()
+		if ti != tj {
+			return ti > tj
+		}
+		return matches[i].ID > matches[j].ID
+	})
+
+	page := Page{Records: matches}
+	if q.Limit > 0 && len(matches) > q.Limit {
+		page.Records = matches[:q.Limit]
+		page.NextCursor = CursorFor(page.Records[q.Limit-1])
+	}
+	return page, nil
+}
+
+func (m *MemoryStore) Dump(ctx context.Context) (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data := make(map[string]string, len(m.alarms))
+	for id, rec := range m.alarms {
+		data[Key(id)] = rec.Value
+	}
+	return data, nil
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryEventLog keeps the most recent maxLen events in process memory. Event
+// IDs use the Redis stream format "<unix ms>-<sequence>".
+type MemoryEventLog struct {
+	mu      sync.RWMutex
+	events  []Event
+	maxLen  int
+	lastMs  int64
+	lastSeq int64
+}
+
+func NewMemoryEventLog(maxLen int) *MemoryEventLog {
+	return &MemoryEventLog{maxLen: maxLen}
+}
+
+func (m *MemoryEventLog) Append(ctx context.Context, events ...Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range events {
+		now := time.Now()
+		ms := now.UnixMilli()
+		if ms <= m.lastMs {
+			ms = m.lastMs
+			m.lastSeq++
+		} else {
+			m.lastMs = ms
+			m.lastSeq = 0
+		}
+		e.ID = fmt.Sprintf("%d-%d", ms, m.lastSeq)
+		e.Time = time.UnixMilli(ms)
+		m.events = append(m.events, e)
+	}
+
+	if m.maxLen > 0 && len(m.events) > m.maxLen {
+		m.events = append([]Event(nil), m.events[len(m.events)-m.maxLen:]...)
+	}
+	return nil
+}
+
+func (m *MemoryEventLog) Events(ctx context.Context, q EventQuery) (EventPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var page EventPage
+	for i := len(m.events) - 1; i >= 0; i-- {
+		e := m.events[i]
+		if q.Before != "" && !eventIDBefore(e.ID, q.Before) {
+			continue
+		}
+		if !q.Matches(e) {
+			continue
+		}
+		if q.Limit > 0 && len(page.Events) == q.Limit {
+			page.NextCursor = page.Events[len(page.Events)-1].ID
+			break
+		}
+		page.Events = append(page.Events, e)
+	}
+	return page, nil
+}
+
+// eventIDBefore reports whether stream ID a sorts before stream ID b.
+func eventIDBefore(a, b string) bool {
+	aMs, aSeq := splitEventID(a)
+	bMs, bSeq := splitEventID(b)
+	return aMs < bMs || (aMs == bMs && aSeq < bSeq)
+}
+
+func splitEventID(id string) (int64, int64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseInt(msPart, 10, 64)
+	seq, _ := strconv.ParseInt(seqPart, 10, 64)
+	return ms, seq
+}
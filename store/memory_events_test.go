@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryEventLogPagesNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	events := NewMemoryEventLog(0)
+	for _, id := range []string{"a", "b", "c"} {
+		events.Append(ctx, Event{Type: EventIngest, AlarmID: id})
+	}
+
+	first, err := events.Events(ctx, EventQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first.Events) != 2 || first.Events[0].AlarmID != "c" || first.Events[1].AlarmID != "b" || first.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+
+	second, _ := events.Events(ctx, EventQuery{Limit: 2, Before: first.NextCursor})
+	if len(second.Events) != 1 || second.Events[0].AlarmID != "a" || second.NextCursor != "" {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+}
+
+func TestMemoryEventLogTrimsToMaxLen(t *testing.T) {
+	ctx := context.Background()
+	events := NewMemoryEventLog(2)
+	events.Append(ctx, Event{AlarmID: "a"}, Event{AlarmID: "b"}, Event{AlarmID: "c"})
+
+	page, _ := events.Events(ctx, EventQuery{})
+	if len(page.Events) != 2 || page.Events[1].AlarmID != "b" {
+		t.Fatalf("expected only the two newest events, got %+v", page.Events)
+	}
+
+	filtered, _ := events.Events(ctx, EventQuery{AlarmID: "c"})
+	if len(filtered.Events) != 1 {
+		t.Fatalf("expected alarm filter to match one event, got %d", len(filtered.Events))
+	}
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStorePutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	if err := s.Put(ctx, Record{ID: "a1", Tag: "ALARM", Value: `{"tag":"ALARM","message":"disk full"}`}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec, err := s.Get(ctx, "a1")
+	if err != nil || rec.Value != `{"tag":"ALARM","message":"disk full"}` {
+		t.Fatalf("unexpected get result %+v, %v", rec, err)
+	}
+
+	deleted, err := s.Delete(ctx, "a1", "missing")
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected one deleted alarm, got %d, %v", deleted, err)
+	}
+
+	if _, err := s.Get(ctx, "a1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryStoreSwapDetectsConflicts(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	old := Record{ID: "a1", Tag: "ALARM", Value: `{"fingerprint":"f1","state":{"status":"open"}}`}
+	s.Put(ctx, old)
+
+	acked := Record{ID: "a1", Tag: "ALARM", Value: `{"fingerprint":"f1","state":{"status":"acknowledged"}}`}
+	if err := s.Swap(ctx, old, acked); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stale := Record{ID: "a1", Tag: "ALARM", Value: `{"fingerprint":"f2","state":{"status":"resolved"}}`}
+	if err := s.Swap(ctx, old, stale); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected a swap against a stale read to conflict, got %v", err)
+	}
+	if err := s.Swap(ctx, Record{ID: "missing"}, stale); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if rec, _ := s.Get(ctx, "a1"); rec.Value != acked.Value {
+		t.Fatalf("expected the acknowledged alarm to be kept, got %s", rec.Value)
+	}
+
+	s.Put(ctx, Record{ID: "a2", Tag: "OTHER", Value: `{"fingerprint":"f1"}`})
+	s.Put(ctx, Record{ID: "a2", Tag: "OTHER", Value: `{"fingerprint":"f3"}`})
+	page, err := s.Query(ctx, Query{Fingerprint: "f1"})
+	if err != nil || len(page.Records) != 1 || page.Records[0].ID != "a1" {
+		t.Fatalf("expected only a1 to have fingerprint f1, got %+v, %v", page.Records, err)
+	}
+}
+
+func TestMemoryStoreQueryByTagAndField(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	s.Put(ctx, Record{ID: "t1", Tag: "INSIGHTS", Value: `{"tag":"INSIGHTS","IP":"192.0.2.10"}`})
+	s.Put(ctx, Record{ID: "t2", Tag: "INSIGHTS", Value: `{"tag":"INSIGHTS","IP":"192.0.2.11"}`})
+	s.Put(ctx, Record{ID: "t3", Tag: "INSIGHTS", Value: `{"v":2,"tag":"INSIGHTS","fields":{"IP":"192.0.2.10"}}`})
+	s.Put(ctx, Record{ID: "a1", Tag: "ALARM", Value: `{"tag":"ALARM","message":"x"}`})
+	s.Put(ctx, Record{ID: "raw", Value: `not json`})
+
+	page, err := s.Query(ctx, Query{Tag: "insights", Fields: map[string]string{"IP": "192.0.2.10"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Records) != 2 {
+		t.Fatalf("expected t1 and t3 to match, got %v", page.Records)
+	}
+
+	all, _ := s.List(ctx)
+	if len(all) != 5 {
+		t.Fatalf("expected list to return every alarm, got %d", len(all))
+	}
+}
+
+func TestMemoryStoreQueryPagesByTimeWithStableCursor(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	// b and c share a timestamp, so the cursor must break the tie by ID.
+	s.Put(ctx, Record{ID: "a", ReceivedAt: base})
+	s.Put(ctx, Record{ID: "b", ReceivedAt: base.Add(time.Hour)})
+	s.Put(ctx, Record{ID: "c", ReceivedAt: base.Add(time.Hour)})
+	s.Put(ctx, Record{ID: "d", ReceivedAt: base.Add(2 * time.Hour)})
+
+	var got []string
+	q := Query{Limit: 1, Until: base.Add(90 * time.Minute)}
+	for {
+		page, err := s.Query(ctx, q)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, rec := range page.Records {
+			got = append(got, rec.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+
+	if len(got) != 3 || got[0] != "c" || got[1] != "b" || got[2] != "a" {
+		t.Fatalf("expected newest-first c, b, a; got %v", got)
+	}
+}
+
+func TestParseCursorRejectsGarbage(t *testing.T) {
+	if _, err := ParseCursor("!!!"); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when an alarm does not exist in the store.
+var ErrNotFound = errors.New("alarm not found")
+
+// ErrConflict is returned by Swap when the alarm changed since it was read.
+var ErrConflict = errors.New("alarm was changed concurrently")
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Record is a stored alarm. Value holds the alarm as a JSON document; Tag and
+// ReceivedAt are kept alongside it so backends can index alarms without
+// parsing the document.
+type Record struct {
+	ID         string
+	Tag        string
+	ReceivedAt time.Time
+	Value      string
+}
+
+// KeyPrefix is prepended to alarm IDs to form the alarm keys shown in logs,
+// notifier payloads and the /api/data dump.
+const KeyPrefix = "alarm:"
+
+// Key returns the alarm key for id, e.g. "alarm:<id>".
+func Key(id string) string {
+	return KeyPrefix + id
+}
+
+// AlarmStore persists alarms keyed by alarm ID. IDs never include the
+// KeyPrefix or a backend-specific namespace.
+type AlarmStore interface {
+	// Put creates or replaces the alarm stored under rec.ID.
+	Put(ctx context.Context, rec Record) error
+	// Swap replaces the alarm stored under old.ID with rec, but only if it
+	// still holds old.Value. It returns ErrNotFound if the alarm is gone and
+	// ErrConflict if it was changed since old was read.
+	Swap(ctx context.Context, old, rec Record) error
+	// Get returns the alarm stored under id, or ErrNotFound.
+	Get(ctx context.Context, id string) (Record, error)
+	// List returns every stored alarm, newest first.
+	List(ctx context.Context) ([]Record, error)
+	// Delete removes the given alarms and reports how many existed.
+	Delete(ctx context.Context, ids ...string) (int, error)
+	// Query returns one page of alarms matching q, newest first.
+	Query(ctx context.Context, q Query) (Page, error)
+	// Dump returns the raw backend contents for diagnostics.
+	Dump(ctx context.Context) (map[string]string, error)
+}
+
+// Query selects alarms by tag, fingerprint, received time range and exact
+// field values. Fingerprint matches the document's "fingerprint" and, unlike
+// Fields, is served from an index. Fields match top-level keys of the alarm
+// document or, failing that, keys of its "fields" object. A zero Limit
+// returns every match.
+type Query struct {
+	Tag         string
+	Fingerprint string
+	Fields      map[string]string
+	Since       time.Time
+	Until       time.Time
+	Limit       int
+	Cursor      string
+}
+
+// Page is one newest-first slice of query results. NextCursor is empty when
+// there are no further results.
+type Page struct {
+	Records    []Record
+	NextCursor string
+}
+
+// Matches reports whether a record satisfies the tag, fingerprint, time
+// range and field filters of the query. Pagination is not considered.
+func (q Query) Matches(rec Record) bool {
+	if q.Tag != "" && !strings.EqualFold(rec.Tag, q.Tag) {
+		return false
+	}
+	if !q.Since.IsZero() && rec.ReceivedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && rec.ReceivedAt.After(q.Until) {
+		return false
+	}
+	if len(q.Fields) == 0 && q.Fingerprint == "" {
+		return true
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(rec.Value), &doc); err != nil {
+		return false
+	}
+	if q.Fingerprint != "" && fieldString(doc["fingerprint"]) != q.Fingerprint {
+		return false
+	}
+	nested, _ := doc["fields"].(map[string]interface{})
+	for field, want := range q.Fields {
+		v, ok := doc[field]
+		if !ok {
+			v = nested[field]
+		}
+		if fieldString(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// FingerprintOf returns the "fingerprint" of an alarm document, or "" if it
+// has none, for backends that index it.
+func FingerprintOf(value string) string {
+	var doc struct {
+		Fingerprint string `json:"fingerprint"`
+	}
+	json.Unmarshal([]byte(value), &doc)
+	return doc.Fingerprint
+}
+
+func fieldString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// Cursor marks the last record of a page. Records are ordered by received
+// time and then by ID, both descending, so the position is stable even when
+// several alarms share a timestamp.
+type Cursor struct {
+	ReceivedAt int64 // Unix milliseconds
+	ID         string
+}
+
+// CursorFor returns the opaque cursor pointing just past rec.
+func CursorFor(rec Record) string {
+	raw := strconv.FormatInt(rec.ReceivedAt.UnixMilli(), 10) + ":" + rec.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a cursor produced by CursorFor.
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	ms, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	receivedAt, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{ReceivedAt: receivedAt, ID: id}, nil
+}
+
+// Includes reports whether a record with the given received time and ID comes
+// after the cursor in newest-first order.
+func (c Cursor) Includes(receivedAtMs int64, id string) bool {
+	return receivedAtMs < c.ReceivedAt || (receivedAtMs == c.ReceivedAt && id < c.ID)
+}
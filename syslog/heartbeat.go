@@ -0,0 +1,273 @@
+package syslog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/notifier"
+	"logvault/silence"
+	"logvault/store"
+)
+
+const senderSilentTag = "SENDER_SILENT"
+const senderSilentIDPrefix = "sender-silent:"
+
+// expectedSender is a heartbeat.senders entry. An address that is an IP or
+// CIDR range is matched against the source IP of messages only, and any
+// other address against the syslog header hostname only, so a sender cannot
+// keep another one alive by naming it in its header.
+type expectedSender struct {
+	name       string
+	address    string
+	ip         net.IP
+	network    *net.IPNet
+	maxSilence time.Duration
+}
+
+func (s expectedSender) matches(clientIP net.IP, hostname string) bool {
+	switch {
+	case s.network != nil:
+		return clientIP != nil && s.network.Contains(clientIP)
+	case s.ip != nil:
+		return clientIP != nil && s.ip.Equal(clientIP)
+	default:
+		return hostname != "" && strings.EqualFold(hostname, s.address)
+	}
+}
+
+// HeartbeatMonitor tracks when each syslog sender was last heard from and
+// raises a "sender silent" alarm when an expected sender stays quiet for
+// longer than its configured interval. The alarm is resolved automatically
+// as soon as traffic from that sender resumes, and reopened if it goes quiet
+// again.
+type HeartbeatMonitor struct {
+	alarms    store.AlarmStore
+	events    store.EventLog
+	silences  *silence.Silencer
+	appConfig config.Config
+	senders   []expectedSender
+	started   time.Time
+
+	// lastSeen and alarmed are keyed by expected sender name, so traffic
+	// from other senders cannot grow them.
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+	alarmed  map[string]bool
+}
+
+// NewHeartbeatMonitor builds a monitor for the senders listed in heartbeat.senders.
+func NewHeartbeatMonitor(alarms store.AlarmStore, events store.EventLog, silences *silence.Silencer, appConfig config.Config) *HeartbeatMonitor {
+	m := &HeartbeatMonitor{
+		alarms:    alarms,
+		events:    events,
+		silences:  silences,
+		appConfig: appConfig,
+		started:   time.Now(),
+		lastSeen:  make(map[string]time.Time),
+		alarmed:   make(map[string]bool),
+	}
+
+	for _, s := range appConfig.Heartbeat.Senders {
+		address := strings.ToLower(strings.TrimSpace(s.Address))
+		if address == "" || s.MaxSilence <= 0 {
+			log.Printf("Ignoring heartbeat sender %q: address and max_silence are required", s.Name)
+			continue
+		}
+		name := strings.TrimSpace(s.Name)
+		if name == "" {
+			name = address
+		}
+		sender := expectedSender{name: name, address: address, ip: net.ParseIP(address), maxSilence: s.MaxSilence}
+		if strings.Contains(address, "/") {
+			_, network, err := net.ParseCIDR(address)
+			if err != nil {
+				log.Printf("Ignoring heartbeat sender %q: %v", s.Name, err)
+				continue
+			}
+			sender.network = network
+		}
+		m.senders = append(m.senders, sender)
+
+		// Pick up alarms raised before a restart so they still get resolved.
+		if alarms != nil {
+			if rec, err := alarms.Get(context.Background(), senderSilentIDPrefix+name); err == nil {
+				doc, _ := alarm.Decode(rec)
+				m.alarmed[name] = doc.Active()
+			}
+		}
+	}
+
+	return m
+}
+
+// Run checks expected senders every interval. It never returns.
+func (m *HeartbeatMonitor) Run(interval time.Duration) {
+	if len(m.senders) == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, sender := range m.silentSenders(time.Now()) {
+			m.raiseSilentAlarm(sender)
+		}
+	}
+}
+
+// Observe records traffic from a sender, identified by its source IP and the
+// hostname reported in the syslog header, and clears any silence alarm for it.
+func (m *HeartbeatMonitor) Observe(clientIP, hostname string) {
+	for _, sender := range m.resumedSenders(clientIP, hostname, time.Now()) {
+		m.clearSilentAlarm(sender)
+	}
+}
+
+func (m *HeartbeatMonitor) resumedSenders(clientIP, hostname string, now time.Time) []expectedSender {
+	ip := net.ParseIP(strings.TrimSpace(clientIP))
+	hostname = strings.TrimSpace(hostname)
+	if hostname == "-" {
+		hostname = ""
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var resumed []expectedSender
+	for _, sender := range m.senders {
+		if !sender.matches(ip, hostname) {
+			continue
+		}
+		m.lastSeen[sender.name] = now
+		if m.alarmed[sender.name] {
+			m.alarmed[sender.name] = false
+			resumed = append(resumed, sender)
+		}
+	}
+	return resumed
+}
+
+func (m *HeartbeatMonitor) silentSenders(now time.Time) []expectedSender {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var silent []expectedSender
+	for _, sender := range m.senders {
+		if m.alarmed[sender.name] {
+			continue
+		}
+		last, ok := m.lastSeen[sender.name]
+		if !ok {
+			last = m.started
+		}
+		if now.Sub(last) > sender.maxSilence {
+			m.alarmed[sender.name] = true
+			silent = append(silent, sender)
+		}
+	}
+	return silent
+}
+
+// LastSeen returns the last time the expected sender with the given name
+// was heard from.
+func (m *HeartbeatMonitor) LastSeen(name string) (time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	last, ok := m.lastSeen[name]
+	return last, ok
+}
+
+func (m *HeartbeatMonitor) raiseSilentAlarm(sender expectedSender) {
+	lastSeen := "never"
+	if last, ok := m.LastSeen(sender.name); ok {
+		lastSeen = last.Format(time.RFC3339)
+	}
+
+	id := senderSilentIDPrefix + sender.name
+	key := store.Key(id)
+	doc := alarm.New(id, senderSilentTag, time.Now())
+	switch {
+	case sender.ip != nil:
+		doc.Source.IP = sender.address
+	case sender.network == nil:
+		doc.Source.Hostname = sender.address
+	}
+	doc.Fields = map[string]string{
+		"message":     fmt.Sprintf("No syslog messages from %s (%s) for more than %s", sender.name, sender.address, sender.maxSilence),
+		"sender":      sender.name,
+		"address":     sender.address,
+		"last_seen":   lastSeen,
+		"max_silence": sender.maxSilence.String(),
+	}
+
+	reopened := false
+	var replaced *store.Record
+	if prev, err := m.alarms.Get(context.Background(), id); err == nil {
+		prevDoc, _ := alarm.Decode(prev)
+		reopened = doc.Recur(prevDoc, "heartbeat")
+		replaced = &prev
+	}
+	silenced := applySilence(m.silences, &doc)
+	rec, err := doc.Record()
+	if err != nil {
+		log.Printf("Failed to marshal sender silent alarm for %s: %v", sender.name, err)
+		return
+	}
+
+	// The previous alarm is only replaced if unchanged since it was read, so
+	// an acknowledgement or comment made meanwhile is carried over.
+	if rec, err = putAlarm(m.alarms, &doc, rec, replaced, "heartbeat"); err != nil {
+		log.Printf("Failed to SET key %s for silent sender: %v", key, err)
+		// Retry on the next check.
+		m.mu.Lock()
+		m.alarmed[sender.name] = false
+		m.mu.Unlock()
+		return
+	}
+	log.Printf("SAVED: Set key %s, sender %s has been silent for more than %s", key, sender.name, sender.maxSilence)
+	if reopened {
+		recordReopen(m.events, rec, "heartbeat")
+	} else {
+		recordIngest(m.events, rec)
+	}
+
+	if !silenced && m.appConfig.ExternalAPI.Enabled && shouldTriggerNotifier(senderSilentTag, m.appConfig.ExternalAPI.TriggerTags) {
+		go notifier.CallExternalAPI(m.appConfig, map[string]string{"key": key, "message": rec.Value, "status": senderSilentTag})
+	}
+}
+
+func (m *HeartbeatMonitor) clearSilentAlarm(sender expectedSender) {
+	id := senderSilentIDPrefix + sender.name
+	key := store.Key(id)
+	message := fmt.Sprintf("Sender %s resumed sending syslog messages", sender.name)
+	doc, err := alarm.Change(context.Background(), m.alarms, m.events, id, alarm.ActionResolve, "heartbeat", message)
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, alarm.ErrInvalidTransition) {
+		log.Printf("Sender %s is sending again; %s is already gone or resolved", sender.name, key)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to resolve key %s for resumed sender: %v", key, err)
+		return
+	}
+	log.Printf("RESOLVED: Resolved key %s, sender %s is sending again", key, sender.name)
+
+	// A silenced alarm was never announced, so neither is its resolution.
+	if !doc.Suppressed() && m.appConfig.ExternalAPI.Enabled && shouldTriggerNotifier(senderSilentTag, m.appConfig.ExternalAPI.TriggerTags) {
+		go notifier.CallExternalAPI(m.appConfig, map[string]string{
+			"key":     key,
+			"message": message,
+			"status":  strings.ToUpper(doc.Status()),
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package syslog
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/store"
+)
+
+func newTestHeartbeatMonitor(t *testing.T, address string, maxSilence time.Duration) *HeartbeatMonitor {
+	t.Helper()
+
+	cfg := config.Config{}
+	cfg.Heartbeat.Senders = []struct {
+		Name       string        `mapstructure:"name"`
+		Address    string        `mapstructure:"address"`
+		MaxSilence time.Duration `mapstructure:"max_silence"`
+	}{
+		{Name: "sensor-1", Address: address, MaxSilence: maxSilence},
+	}
+
+	return NewHeartbeatMonitor(nil, nil, nil, cfg)
+}
+
+func TestHeartbeatMonitorRaisesAfterMaxSilence(t *testing.T) {
+	m := newTestHeartbeatMonitor(t, "10.0.0.5", time.Minute)
+	now := m.started
+
+	m.resumedSenders("10.0.0.5", "", now)
+
+	if silent := m.silentSenders(now.Add(30 * time.Second)); len(silent) != 0 {
+		t.Fatalf("expected no silent senders yet, got %d", len(silent))
+	}
+
+	silent := m.silentSenders(now.Add(2 * time.Minute))
+	if len(silent) != 1 || silent[0].name != "sensor-1" {
+		t.Fatalf("expected sensor-1 to be reported silent, got %+v", silent)
+	}
+
+	if again := m.silentSenders(now.Add(3 * time.Minute)); len(again) != 0 {
+		t.Fatal("expected silent alarm to be raised only once")
+	}
+}
+
+func TestHeartbeatMonitorClearsWhenTrafficResumes(t *testing.T) {
+	m := newTestHeartbeatMonitor(t, "fw01.example.net", time.Minute)
+	now := m.started
+
+	if silent := m.silentSenders(now.Add(2 * time.Minute)); len(silent) != 1 {
+		t.Fatalf("expected never-seen sender to go silent, got %d", len(silent))
+	}
+
+	resumed := m.resumedSenders("192.0.2.1", "FW01.example.net", now.Add(3*time.Minute))
+	if len(resumed) != 1 || resumed[0].name != "sensor-1" {
+		t.Fatalf("expected sensor-1 to resume by hostname, got %+v", resumed)
+	}
+
+	if again := m.resumedSenders("192.0.2.1", "fw01.example.net", now.Add(4*time.Minute)); len(again) != 0 {
+		t.Fatal("expected resume to be reported only once")
+	}
+}
+
+func TestHeartbeatMonitorMatchesIPSendersBySourceIPOnly(t *testing.T) {
+	m := newTestHeartbeatMonitor(t, "10.0.0.5", time.Minute)
+	now := m.started
+
+	if silent := m.silentSenders(now.Add(2 * time.Minute)); len(silent) != 1 {
+		t.Fatalf("expected never-seen sender to go silent, got %d", len(silent))
+	}
+
+	if resumed := m.resumedSenders("203.0.113.7", "10.0.0.5", now.Add(3*time.Minute)); len(resumed) != 0 {
+		t.Fatalf("expected a header hostname not to resume an IP sender, got %+v", resumed)
+	}
+	if _, ok := m.LastSeen("sensor-1"); ok {
+		t.Fatal("expected a spoofed hostname not to count as a heartbeat")
+	}
+
+	if resumed := m.resumedSenders("10.0.0.5", "", now.Add(4*time.Minute)); len(resumed) != 1 {
+		t.Fatalf("expected the source IP to resume the sender, got %+v", resumed)
+	}
+}
+
+func TestHeartbeatMonitorMatchesCIDRSenders(t *testing.T) {
+	m := newTestHeartbeatMonitor(t, "10.0.0.0/24", time.Minute)
+	now := time.Now()
+
+	m.resumedSenders("10.0.0.42", "", now)
+	if last, ok := m.LastSeen("sensor-1"); !ok || !last.Equal(now) {
+		t.Fatalf("expected an IP in the range to count for the sender, got %v %v", last, ok)
+	}
+}
+
+func TestHeartbeatMonitorOnlyTracksExpectedSenders(t *testing.T) {
+	m := newTestHeartbeatMonitor(t, "10.0.0.5", time.Minute)
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		m.resumedSenders("203.0.113.7", fmt.Sprintf("host-%d", i), now)
+	}
+	if len(m.lastSeen) != 0 {
+		t.Fatalf("expected unexpected senders not to be tracked, got %d entries", len(m.lastSeen))
+	}
+}
+
+// racingStore lets another writer change an alarm right after the first Get,
+// as a concurrent action between a read and its write would.
+type racingStore struct {
+	*store.MemoryStore
+	race func()
+}
+
+func (s *racingStore) Get(ctx context.Context, id string) (store.Record, error) {
+	rec, err := s.MemoryStore.Get(ctx, id)
+	if race := s.race; race != nil {
+		s.race = nil
+		race()
+	}
+	return rec, err
+}
+
+func TestRaiseSilentAlarmKeepsConcurrentComment(t *testing.T) {
+	ctx := context.Background()
+	alarms := &racingStore{MemoryStore: store.NewMemoryStore()}
+	events := store.NewMemoryEventLog(0)
+	cfg := config.Config{}
+	cfg.Heartbeat.Senders = []struct {
+		Name       string        `mapstructure:"name"`
+		Address    string        `mapstructure:"address"`
+		MaxSilence time.Duration `mapstructure:"max_silence"`
+	}{
+		{Name: "sensor-1", Address: "10.0.0.5", MaxSilence: time.Minute},
+	}
+	m := NewHeartbeatMonitor(alarms, events, nil, cfg)
+
+	m.raiseSilentAlarm(m.senders[0])
+	id := senderSilentIDPrefix + "sensor-1"
+	if _, err := alarm.Change(ctx, alarms, events, id, alarm.ActionResolve, "heartbeat", "resumed"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	alarms.race = func() {
+		if _, err := alarm.AddComment(ctx, alarms.MemoryStore, events, id, "analyst", "sensor rebooted"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	m.raiseSilentAlarm(m.senders[0])
+
+	rec, _ := alarms.Get(ctx, id)
+	doc, _ := alarm.Decode(rec)
+	if doc.Status() != alarm.StatusOpen || len(doc.Comments) != 1 {
+		t.Fatalf("expected the alarm reopened with the comment kept, got %s with %+v", doc.Status(), doc.Comments)
+	}
+}
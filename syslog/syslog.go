@@ -1,27 +1,48 @@
 package syslog
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strconv" // Added for string to int conversion
 	"strings"
+	"sync"
 	"time" // Added for time formatting
 
 	"gopkg.in/mcuadros/go-syslog.v2"
 
+	"logvault/alarm"
+	"logvault/archive"
 	"logvault/config"
+	"logvault/incident"
 	"logvault/internal/allowlist"
 	"logvault/notifier"
-	"logvault/redis"
+	"logvault/silence"
+	"logvault/store"
 )
 
-const alarmPrefix = "alarm:"
+// maxRejectionSources caps how many senders tag rejections are counted for
+// separately; rejections from further senders are counted under "other".
+const maxRejectionSources = 1024
+
+var tagRejections = struct {
+	sync.Mutex
+	total    int64
+	bySource map[string]int64
+}{bySource: make(map[string]int64)}
+
+// TagRejectionStats counts the messages rejected by syslog.sender_tags.
+type TagRejectionStats struct {
+	Total    int64            `json:"total"`
+	BySource map[string]int64 `json:"by_source,omitempty"`
+}
 
 // StartServer initializes and starts the syslog server
-func StartServer(rdb *redis.RedisClient, appConfig config.Config) *syslog.Server {
+func StartServer(alarms store.AlarmStore, events store.EventLog, archiver *archive.Writer, silences *silence.Silencer, incidents *incident.Grouper, appConfig config.Config) *syslog.Server {
 	channel := make(syslog.LogPartsChannel)
 	handler := syslog.NewChannelHandler(channel)
 
@@ -38,35 +59,75 @@ func StartServer(rdb *redis.RedisClient, appConfig config.Config) *syslog.Server
 		log.Fatalf("Failed to boot syslog server: %v", err)
 	}
 
-	go processLogs(rdb, appConfig, channel)
+	monitor := NewHeartbeatMonitor(alarms, events, silences, appConfig)
+	go monitor.Run(appConfig.Heartbeat.CheckInterval)
+
+	go processLogs(alarms, events, archiver, silences, incidents, appConfig, channel, monitor)
 	return server
 }
 
 func shouldTriggerNotifier(tag string, triggerTags string) bool {
-	if triggerTags == "" {
+	return notifier.ShouldTrigger(tag, triggerTags)
+}
+
+// applySilence flags doc if an active silence or maintenance window matches
+// it, and reports whether it did. Silenced alarms are stored but not notified.
+func applySilence(silences *silence.Silencer, doc *alarm.Document) bool {
+	if !silences.Suppress(doc) {
 		return false
 	}
-	incomingTag := strings.ToUpper(tag)
-	tags := strings.Split(triggerTags, ",")
-	for _, t := range tags {
-		if incomingTag == strings.ToUpper(strings.TrimSpace(t)) {
-			return true
-		}
+	if doc.SilencedBy != "" {
+		log.Printf("SILENCED: %s alarm %s matches silence %s", doc.Tag, store.Key(doc.ID), doc.SilencedBy)
 	}
-	return false
+	if doc.Maintenance != "" {
+		log.Printf("SILENCED: %s alarm %s raised during maintenance window %q", doc.Tag, store.Key(doc.ID), doc.Maintenance)
+	}
+	return true
 }
 
-func processLogs(rdb *redis.RedisClient, appConfig config.Config, channel syslog.LogPartsChannel) {
+// groupIncident files doc, once stored as rec, into an incident if a grouping
+// rule applies, and updates rec with the incident. notify says whether doc
+// would be notified on its own; grouped alarms are not, and groupIncident
+// reports whether the incident is to be notified for doc instead.
+func groupIncident(incidents *incident.Grouper, doc *alarm.Document, rec *store.Record, notify bool) (*incident.Incident, bool) {
+	inc, first, err := incidents.Group(context.Background(), doc, notify)
+	if err != nil {
+		log.Printf("Failed to group %s alarm %s into an incident: %v", doc.Tag, store.Key(doc.ID), err)
+		return nil, false
+	}
+	if inc == nil {
+		return nil, false
+	}
+	if inc.Count == 1 {
+		log.Printf("INCIDENT: %s alarm %s opened incident %s (%s)", doc.Tag, store.Key(doc.ID), inc.ID, inc.Rule)
+	} else {
+		log.Printf("INCIDENT: %s alarm %s joined incident %s (%s, %d alarms)", doc.Tag, store.Key(doc.ID), inc.ID, inc.Rule, inc.Count)
+	}
+	if updated, err := doc.Record(); err == nil {
+		*rec = updated
+	}
+	return inc, first
+}
+
+func processLogs(alarms store.AlarmStore, events store.EventLog, archiver *archive.Writer, silences *silence.Silencer, incidents *incident.Grouper, appConfig config.Config, channel syslog.LogPartsChannel, monitor *HeartbeatMonitor) {
 	allowed, err := allowlist.New(appConfig.Syslog.AllowedIPs)
 	if err != nil {
 		log.Fatalf("Invalid syslog.allowed_ips configuration: %v", err)
 	}
 
+	rules := make([]allowlist.TagRule, 0, len(appConfig.Syslog.SenderTags))
+	for _, rule := range appConfig.Syslog.SenderTags {
+		rules = append(rules, allowlist.TagRule{Sources: rule.Sources, Tags: rule.Tags})
+	}
+	tagPolicy, err := allowlist.NewTagPolicy(rules)
+	if err != nil {
+		log.Fatalf("Invalid syslog.sender_tags configuration: %v", err)
+	}
+
 	for logParts := range channel {
 		if !isAllowedSyslogSender(logParts, allowed) {
 			continue
 		}
-
 		log.Printf("DEBUG: Received raw syslog parts: %+v", logParts)
 		tag, message := "", "" // Declare once
 
@@ -82,6 +143,13 @@ func processLogs(rdb *redis.RedisClient, appConfig config.Config, channel syslog
 			}
 		}
 
+		if !isAllowedSyslogTag(logParts, tag, tagPolicy) {
+			continue
+		}
+		// Only messages that pass the tag policy count as a heartbeat, so a
+		// spoofed message with a rejected tag cannot keep a sender alive.
+		monitor.Observe(senderIdentity(logParts))
+
 		contentVal, ok := logParts["content"]
 		if ok && contentVal != nil {
 			message = contentVal.(string)
@@ -98,12 +166,43 @@ func processLogs(rdb *redis.RedisClient, appConfig config.Config, channel syslog
 			continue
 		}
 
+		severity := severityName(logParts["severity"])
+		clientIP, hostname := senderIdentity(logParts)
+		source := alarm.Source{IP: clientIP, Hostname: hostname}
+
+		var rec *store.Record
 		switch strings.ToUpper(tag) {
 		case "INSIGHTS":
-			parseThreatMessageAndSave(rdb, message, appConfig, tag)
+			rec = parseThreatMessageAndSave(alarms, events, message, appConfig, tag, severity, source, silences, incidents)
 		default:
-			saveWithRandomKey(rdb, message, tag)
+			rec = saveWithRandomKey(alarms, events, message, tag, severity, source, appConfig, silences, incidents)
 		}
+		archiveEvent(archiver, logParts, tag, severity, message, rec)
+	}
+}
+
+// archiveEvent writes an accepted message to the local archive, together with
+// the alarm it was parsed into. Messages dropped by the parser are archived
+// without an alarm.
+func archiveEvent(archiver *archive.Writer, logParts map[string]interface{}, tag, severity, message string, rec *store.Record) {
+	clientIP, hostname := senderIdentity(logParts)
+	entry := archive.Entry{
+		Time:     time.Now(),
+		Source:   clientIP,
+		Hostname: hostname,
+		Tag:      tag,
+		Severity: severity,
+		Raw:      message,
+	}
+	if rec != nil {
+		entry.Time = rec.ReceivedAt
+		entry.AlarmID = rec.ID
+		if json.Valid([]byte(rec.Value)) {
+			entry.Parsed = json.RawMessage(rec.Value)
+		}
+	}
+	if err := archiver.Write(entry); err != nil {
+		log.Printf("Failed to archive message with tag %s: %v", tag, err)
 	}
 }
 
@@ -131,7 +230,83 @@ func isAllowedSyslogSender(logParts map[string]interface{}, allowed *allowlist.I
 	return false
 }
 
-func parseThreatMessageAndSave(rdb *redis.RedisClient, message string, appConfig config.Config, tag string) {
+func isAllowedSyslogTag(logParts map[string]interface{}, tag string, policy *allowlist.TagPolicy) bool {
+	if !policy.Enabled() {
+		return true
+	}
+
+	clientAddr, _ := logParts["client"].(string)
+	clientIP := allowlist.ParseRemoteHost(clientAddr)
+	if policy.Allows(clientIP, tag) {
+		return true
+	}
+
+	// Count per source IP only: the tag is chosen by the sender, so keying
+	// on it would let one sender grow the counters without bound.
+	source := "unknown"
+	if clientIP != nil {
+		source = clientIP.String()
+	}
+	tagRejections.Lock()
+	if _, ok := tagRejections.bySource[source]; !ok && len(tagRejections.bySource) >= maxRejectionSources {
+		source = "other"
+	}
+	tagRejections.total++
+	tagRejections.bySource[source]++
+	count := tagRejections.bySource[source]
+	tagRejections.Unlock()
+
+	log.Printf("Denied syslog message with tag %q from %q: tag is not allowed for this sender in syslog.sender_tags (%d rejected from this sender so far)", tag, clientAddr, count)
+	return false
+}
+
+// TagRejections returns how many messages were rejected by
+// syslog.sender_tags, in total and per source IP.
+func TagRejections() TagRejectionStats {
+	tagRejections.Lock()
+	defer tagRejections.Unlock()
+
+	bySource := make(map[string]int64, len(tagRejections.bySource))
+	for k, v := range tagRejections.bySource {
+		bySource[k] = v
+	}
+	return TagRejectionStats{Total: tagRejections.total, BySource: bySource}
+}
+
+// severityName maps a numeric syslog severity to its keyword, e.g. 6 to "info".
+func severityName(v interface{}) string {
+	n, ok := v.(int)
+	if !ok || n < 0 || n >= len(alarm.Severities) {
+		return ""
+	}
+	return alarm.Severities[n]
+}
+
+// senderIdentity returns the source IP and the syslog header hostname of a message.
+func senderIdentity(logParts map[string]interface{}) (string, string) {
+	clientIP, hostname := "", ""
+	if clientAddr, ok := logParts["client"].(string); ok {
+		if ip := allowlist.ParseRemoteHost(clientAddr); ip != nil {
+			clientIP = ip.String()
+		}
+	}
+	if h, ok := logParts["hostname"].(string); ok {
+		hostname = h
+	}
+	return clientIP, hostname
+}
+
+// senderFingerprint is the part of an alarm fingerprint naming its sender.
+func senderFingerprint(source alarm.Source) string {
+	if source.IP != "" {
+		return source.IP
+	}
+	return strings.ToLower(source.Hostname)
+}
+
+// parseThreatMessageAndSave stores a THREAT message as a JSON alarm and
+// returns the record it built, or nil when the message was dropped.
+func parseThreatMessageAndSave(alarms store.AlarmStore, events store.EventLog, message string, appConfig config.Config, tag string, severity string, source alarm.Source, silences *silence.Silencer, incidents *incident.Grouper) *store.Record {
 	// Define the field names in order
 	fields := []string{
 		"Score", "DetectTime", "DetectType", "DetectSubType", "FileName",
@@ -144,37 +319,39 @@ func parseThreatMessageAndSave(rdb *redis.RedisClient, message string, appConfig
 
 	if err := validateThreatMessage(values, len(fields)); err != nil {
 		log.Printf("Dropped invalid THREAT message for tag %s: %v", tag, err)
-		return
+		return nil
 	}
 
 	// Generate a random key for the new entry
 	randomBytes := make([]byte, 16)
 	if _, err := rand.Read(randomBytes); err != nil {
 		log.Printf("Failed to generate random key for THREAT log: %v", err)
-		return
+		return nil
 	}
-	key := alarmPrefix + hex.EncodeToString(randomBytes)
+	id := hex.EncodeToString(randomBytes)
 
-	// Create a map to hold the structured data
-	jsonData := make(map[string]interface{})
-	jsonData["tag"] = tag
+	doc := alarm.New(id, tag, time.Now())
+	doc.Severity = severity
+	doc.Source = source
+	doc.Raw = message
+	doc.Fields = make(map[string]string, len(fields))
 
-	// Populate the map with parsed data
+	// Populate the fields with parsed data
 	for i, field := range fields {
 		if i < len(values) {
-			jsonData[field] = values[i]
+			doc.Fields[field] = values[i]
 		} else {
-			jsonData[field] = "" // Assign empty string if value is missing
+			doc.Fields[field] = "" // Assign empty string if value is missing
 		}
 	}
 
 	// Add any extra fields from the log message
 	if len(values) > len(fields) {
-		jsonData["extra_data"] = strings.Join(values[len(fields):], "`")
+		doc.Fields["extra_data"] = strings.Join(values[len(fields):], "`")
 	}
 
 	// Format DetectTime if it's a Unix timestamp
-	if dtVal, ok := jsonData["DetectTime"].(string); ok && dtVal != "" {
+	if dtVal := doc.Fields["DetectTime"]; dtVal != "" {
 		if timestamp, err := strconv.ParseInt(dtVal, 10, 64); err == nil {
 			// Load KST location
 			loc, err := time.LoadLocation("Asia/Seoul")
@@ -184,31 +361,146 @@ func parseThreatMessageAndSave(rdb *redis.RedisClient, message string, appConfig
 			}
 			// Assuming milliseconds, convert to KST time
 			t := time.Unix(timestamp/1000, (timestamp%1000)*int64(time.Millisecond)).In(loc)
-			jsonData["DetectTime"] = t.Format("2006-01-02 15:04:05")
+			doc.Fields["DetectTime"] = t.Format("2006-01-02 15:04:05")
 		} else {
 			log.Printf("Failed to parse DetectTime '%s': %v", dtVal, err)
 		}
 	}
 
-	// Marshal the map into a JSON string
-	jsonBytes, err := json.Marshal(jsonData)
+	doc.Fingerprint = alarm.Fingerprint(tag, senderFingerprint(source), doc.Fields["RuleName"], doc.Fields["DetectType"], doc.Fields["DetectSubType"], doc.Fields["IP"], doc.Fields["FileName"])
+	replaced := reopenRecurrence(alarms, &doc, appConfig)
+	silenced := applySilence(silences, &doc)
+	key := store.Key(doc.ID)
+
+	// Encode the alarm document
+	rec, err := doc.Record()
 	if err != nil {
 		log.Printf("Failed to marshal THREAT data: %v. Falling back to raw log.", err)
 		// Fallback to saving the raw message if JSON marshaling fails
-		saveWithRandomKey(rdb, message, tag)
-		return
+		return saveWithRandomKey(alarms, events, message, tag, severity, source, appConfig, silences, incidents)
 	}
 
-	// Save the JSON string to Redis
-	jsonString := string(jsonBytes)
-	if err := rdb.Set(key, jsonString, 0); err != nil {
+	// Save the alarm to the store
+	if rec, err = putAlarm(alarms, &doc, rec, replaced, "syslog"); err != nil {
 		log.Printf("Failed to SET key %s for THREAT log: %v", key, err)
 	} else {
 		log.Printf("SAVED: Set key %s for THREAT message (as JSON)", key)
-		if appConfig.ExternalAPI.Enabled && shouldTriggerNotifier(tag, appConfig.ExternalAPI.TriggerTags) {
-			go notifier.CallExternalAPI(appConfig, map[string]string{"key": key, "message": jsonString, "status": tag})
+		recordSaved(events, rec, replaced != nil)
+		notify := !silenced && appConfig.ExternalAPI.Enabled && shouldTriggerNotifier(tag, appConfig.ExternalAPI.TriggerTags)
+		switch inc, first := groupIncident(incidents, &doc, &rec, notify); {
+		case first:
+			incident.Notify(appConfig, *inc, incident.StatusNotified)
+		case inc == nil && notify:
+			go notifier.CallExternalAPI(appConfig, map[string]string{"key": key, "message": rec.Value, "status": tag})
 		}
 	}
+	return &rec
+}
+
+// reopenRecurrence looks up the newest alarm with doc's fingerprint whose
+// last occurrence is within lifecycle.reopen_window. If that alarm is resolved
+// or closed, doc takes its place and reopens it, and the replaced record is
+// returned for putAlarm; otherwise doc stays a new alarm and nil is returned.
+func reopenRecurrence(alarms store.AlarmStore, doc *alarm.Document, appConfig config.Config) *store.Record {
+	window := appConfig.Lifecycle.ReopenWindow
+	if window <= 0 || doc.Fingerprint == "" {
+		return nil
+	}
+
+	page, err := alarms.Query(context.Background(), store.Query{
+		Fingerprint: doc.Fingerprint,
+		Since:       doc.ReceivedAt.Add(-window),
+		Limit:       1,
+	})
+	if err != nil {
+		log.Printf("Failed to look up earlier occurrences of %s alarm: %v", doc.Tag, err)
+		return nil
+	}
+	if len(page.Records) == 0 {
+		return nil
+	}
+
+	prev, _ := alarm.Decode(page.Records[0])
+	if prev.Active() || !doc.Recur(prev, "syslog") {
+		return nil
+	}
+	return &page.Records[0]
+}
+
+// maxReopenAttempts bounds how often putAlarm retries replacing an alarm that
+// other writers keep changing.
+const maxReopenAttempts = 10
+
+// putAlarm stores rec, the record of doc. When doc takes over replaced, it
+// only replaces that alarm if it is unchanged since it was read, so an action
+// or comment made in the meantime is not lost; otherwise doc takes over the
+// current alarm again, recurring as by.
+func putAlarm(alarms store.AlarmStore, doc *alarm.Document, rec store.Record, replaced *store.Record, by string) (store.Record, error) {
+	ctx := context.Background()
+	if replaced == nil {
+		return rec, alarms.Put(ctx, rec)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := alarms.Swap(ctx, *replaced, rec)
+		if errors.Is(err, store.ErrNotFound) {
+			return rec, alarms.Put(ctx, rec) // Deleted meanwhile; keep the recurrence
+		}
+		if !errors.Is(err, store.ErrConflict) || attempt == maxReopenAttempts {
+			return rec, err
+		}
+
+		current, err := alarms.Get(ctx, replaced.ID)
+		if err != nil {
+			return rec, err
+		}
+		prev, _ := alarm.Decode(current)
+		doc.Recur(prev, by)
+		if rec, err = doc.Record(); err != nil {
+			return rec, err
+		}
+		replaced = &current
+	}
+}
+
+// recordSaved adds a stored alarm to the event history as a new alarm or,
+// if it replaced a resolved one, as a reopen.
+func recordSaved(events store.EventLog, rec store.Record, reopened bool) {
+	if reopened {
+		recordReopen(events, rec, "syslog")
+		return
+	}
+	recordIngest(events, rec)
+}
+
+// recordReopen adds an alarm that recurred after being resolved or closed to
+// the event history.
+func recordReopen(events store.EventLog, rec store.Record, actor string) {
+	err := events.Append(context.Background(), store.Event{
+		Type:    store.EventReopen,
+		AlarmID: rec.ID,
+		Tag:     rec.Tag,
+		Actor:   actor,
+		Message: "Recurred",
+		Data:    rec.Value,
+	})
+	if err != nil {
+		log.Printf("Failed to record reopen event for key %s: %v", store.Key(rec.ID), err)
+	}
+}
+
+// recordIngest adds a newly stored alarm to the event history.
+func recordIngest(events store.EventLog, rec store.Record) {
+	err := events.Append(context.Background(), store.Event{
+		Type:    store.EventIngest,
+		AlarmID: rec.ID,
+		Tag:     rec.Tag,
+		Actor:   "syslog",
+		Data:    rec.Value,
+	})
+	if err != nil {
+		log.Printf("Failed to record ingest event for key %s: %v", store.Key(rec.ID), err)
+	}
 }
 
 func validateThreatMessage(values []string, expectedFieldCount int) error {
@@ -239,69 +531,74 @@ func validateThreatMessage(values []string, expectedFieldCount int) error {
 	return nil
 }
 
-func saveWithRandomKey(rdb *redis.RedisClient, message string, tag string) {
+// saveWithRandomKey stores a message under a random alarm ID and returns the
+// record it built, or nil when the message was dropped.
+func saveWithRandomKey(alarms store.AlarmStore, events store.EventLog, message string, tag string, severity string, source alarm.Source, appConfig config.Config, silences *silence.Silencer, incidents *incident.Grouper) *store.Record {
 	randomBytes := make([]byte, 16)
 	if _, err := rand.Read(randomBytes); err != nil {
 		log.Printf("Failed to generate random key: %v", err)
-		return
+		return nil
 	}
-	key := alarmPrefix + hex.EncodeToString(randomBytes)
-
-	data := map[string]string{"tag": tag, "message": message}
-	jsonBytes, err := json.Marshal(data)
+	id := hex.EncodeToString(randomBytes)
+
+	doc := alarm.New(id, tag, time.Now())
+	doc.Severity = severity
+	doc.Source = source
+	doc.Raw = message
+	doc.Fingerprint = alarm.Fingerprint(tag, senderFingerprint(source), message)
+	replaced := reopenRecurrence(alarms, &doc, appConfig)
+	silenced := applySilence(silences, &doc)
+	key := store.Key(doc.ID)
+
+	rec, err := doc.Record()
 	if err != nil {
 		log.Printf("Failed to marshal data for random key: %v", err)
-		return
+		return nil
 	}
 
-	if err := rdb.Set(key, string(jsonBytes), 0); err != nil {
+	if rec, err = putAlarm(alarms, &doc, rec, replaced, "syslog"); err != nil {
 		log.Printf("Failed to SET key %s: %v", key, err)
 	} else {
 		log.Printf("SAVED: Set key %s for message with tag %s", key, tag)
+		recordSaved(events, rec, replaced != nil)
+		notify := !silenced && appConfig.ExternalAPI.Enabled && shouldTriggerNotifier(tag, appConfig.ExternalAPI.TriggerTags)
+		if inc, first := groupIncident(incidents, &doc, &rec, notify); first {
+			incident.Notify(appConfig, *inc, incident.StatusNotified)
+		}
 	}
+	return &rec
 }
 
-func parseAndSaveAsJSON(rdb *redis.RedisClient, message string, appConfig config.Config, tag string) {
+func parseAndSaveAsJSON(alarms store.AlarmStore, events store.EventLog, message string, appConfig config.Config, tag string, source alarm.Source) {
 	randomBytes := make([]byte, 16)
 	if _, err := rand.Read(randomBytes); err != nil {
 		log.Printf("Failed to generate random key: %v", err)
 		return
 	}
-	key := alarmPrefix + hex.EncodeToString(randomBytes)
+	id := hex.EncodeToString(randomBytes)
+	key := store.Key(id)
 
 	parts := strings.Split(message, "`")
-	jsonData := make(map[string]interface{})
-	jsonData["tag"] = tag // Add the tag to the JSON data
-
+	doc := alarm.New(id, tag, time.Now())
+	doc.Source = source
+	doc.Raw = message
+	doc.Fields = make(map[string]string, len(parts))
 	for i, part := range parts {
-		var v interface{}
-		// Attempt to unmarshal the part as a JSON object
-		if err := json.Unmarshal([]byte(part), &v); err == nil {
-			jsonData[fmt.Sprintf("field_%d", i)] = v
-		} else {
-			jsonData[fmt.Sprintf("field_%d", i)] = part
-		}
+		doc.Fields[fmt.Sprintf("field_%d", i)] = part
 	}
 
-	jsonBytes, err := json.Marshal(jsonData)
+	rec, err := doc.Record()
 	if err != nil {
 		log.Printf("Failed to marshal JSON: %v", err)
-		// Fallback to saving the raw message if JSON marshaling fails
-		data := map[string]string{"tag": tag, "message": message}
-		fallbackBytes, _ := json.Marshal(data)
-		if err := rdb.Set(key, string(fallbackBytes), 0); err != nil {
-			log.Printf("Failed to SET key %s (raw): %v", key, err)
-		}
 		return
 	}
-
-	jsonString := string(jsonBytes)
-	if err := rdb.Set(key, jsonString, 0); err != nil {
+	if err := alarms.Put(context.Background(), rec); err != nil {
 		log.Printf("Failed to SET key %s (JSON): %v", key, err)
 	} else {
 		log.Printf("SAVED: Set key %s for message (as JSON)", key)
+		recordIngest(events, rec)
 		if appConfig.ExternalAPI.Enabled && shouldTriggerNotifier(tag, appConfig.ExternalAPI.TriggerTags) {
-			go notifier.CallExternalAPI(appConfig, map[string]string{"key": key, "message": jsonString, "status": tag})
+			go notifier.CallExternalAPI(appConfig, map[string]string{"key": key, "message": rec.Value, "status": tag})
 		}
 	}
 }
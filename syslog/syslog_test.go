@@ -1,9 +1,17 @@
 package syslog
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/incident"
 	"logvault/internal/allowlist"
+	"logvault/silence"
+	"logvault/store"
 )
 
 func TestIsAllowedSyslogSenderAllowsAllWhenDisabled(t *testing.T) {
@@ -68,3 +76,135 @@ func TestValidateThreatMessageRejectsInvalidDetectTime(t *testing.T) {
 		t.Fatal("expected payload with invalid DetectTime to be rejected")
 	}
 }
+
+func TestIsAllowedSyslogTagCountsRejections(t *testing.T) {
+	policy, err := allowlist.NewTagPolicy([]allowlist.TagRule{
+		{Sources: []string{"10.0.0.0/8"}, Tags: []string{"ALARM"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logParts := map[string]interface{}{"client": "10.1.2.3:514"}
+	if !isAllowedSyslogTag(logParts, "alarm", policy) {
+		t.Fatal("expected bound tag to be allowed")
+	}
+
+	before := TagRejections()
+	for _, tag := range []string{"INSIGHTS", "random-1", "random-2"} {
+		if isAllowedSyslogTag(logParts, tag, policy) {
+			t.Fatalf("expected unbound tag %q to be denied", tag)
+		}
+	}
+	after := TagRejections()
+	if got := after.BySource["10.1.2.3"] - before.BySource["10.1.2.3"]; got != 3 {
+		t.Fatalf("expected 3 rejections counted for the source, got %d", got)
+	}
+	if got := after.Total - before.Total; got != 3 {
+		t.Fatalf("expected total to grow by 3, got %d", got)
+	}
+}
+
+func TestSaveWithRandomKeyReopensResolvedRecurrence(t *testing.T) {
+	cfg := config.Config{}
+	cfg.Lifecycle.ReopenWindow = time.Hour
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	source := alarm.Source{IP: "10.0.0.5"}
+
+	first := saveWithRandomKey(alarms, events, "disk full", "ALARM", "err", source, cfg, nil, nil)
+	if second := saveWithRandomKey(alarms, events, "disk full", "ALARM", "err", source, cfg, nil, nil); second.ID == first.ID {
+		t.Fatal("expected a recurrence of an open alarm to be stored as a new alarm")
+	}
+
+	all, _ := alarms.List(context.Background())
+	for _, rec := range all {
+		if _, err := alarm.Change(context.Background(), alarms, events, rec.ID, alarm.ActionResolve, "admin", ""); err != nil {
+			t.Fatalf("failed to resolve alarm: %v", err)
+		}
+	}
+
+	third := saveWithRandomKey(alarms, events, "disk full", "ALARM", "err", source, cfg, nil, nil)
+	if third.ID != all[0].ID {
+		t.Fatalf("expected the newest resolved occurrence %s to be reopened, got %s", all[0].ID, third.ID)
+	}
+	doc, _ := alarm.Decode(*third)
+	if doc.Status() != alarm.StatusOpen || doc.State.History[len(doc.State.History)-1].Action != store.EventReopen {
+		t.Fatalf("expected reopened alarm with a reopen transition, got %+v", doc.State)
+	}
+	if remaining, _ := alarms.List(context.Background()); len(remaining) != 2 {
+		t.Fatalf("expected no new alarm for the recurrence, got %d alarms", len(remaining))
+	}
+}
+
+func TestSaveFlagsSilencedAlarms(t *testing.T) {
+	ctx := context.Background()
+	silences := silence.New(store.NewMemoryStore(), 0, nil)
+	s, err := silences.Create(ctx, silence.Silence{Matchers: silence.Matchers{Tag: "ALARM", Sender: "10.0.0.0/24"}, EndsAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("failed to create silence: %v", err)
+	}
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+
+	silenced := saveWithRandomKey(alarms, events, "disk full", "ALARM", "err", alarm.Source{IP: "10.0.0.5"}, config.Config{}, silences, nil)
+	if doc, _ := alarm.Decode(*silenced); doc.SilencedBy != s.ID {
+		t.Fatalf("expected alarm from a silenced sender to be flagged, got %+v", doc)
+	}
+	other := saveWithRandomKey(alarms, events, "disk full", "ALARM", "err", alarm.Source{IP: "10.0.1.5"}, config.Config{}, silences, nil)
+	if doc, _ := alarm.Decode(*other); doc.SilencedBy != "" {
+		t.Fatalf("expected alarm from another sender not to be flagged, got %q", doc.SilencedBy)
+	}
+	if stored, _ := alarms.List(ctx); len(stored) != 2 {
+		t.Fatalf("expected silenced alarms to be stored, got %d alarms", len(stored))
+	}
+}
+
+func TestSaveGroupsAlarmsIntoIncidents(t *testing.T) {
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	incidents, err := incident.New(alarms, store.NewMemoryStore(), events, []incident.Rule{
+		{Name: "per-sender", Tag: "ALARM", Fields: []string{"sender"}, Window: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	source := alarm.Source{IP: "10.0.0.5"}
+
+	first := saveWithRandomKey(alarms, events, "disk full", "ALARM", "err", source, config.Config{}, nil, incidents)
+	second := saveWithRandomKey(alarms, events, "fan failure", "ALARM", "err", source, config.Config{}, nil, incidents)
+	a, _ := alarm.Decode(*first)
+	b, _ := alarm.Decode(*second)
+	if a.Incident == "" || a.Incident != b.Incident {
+		t.Fatalf("expected both alarms from the sender in one incident, got %q and %q", a.Incident, b.Incident)
+	}
+	inc, err := incidents.Get(context.Background(), a.Incident)
+	if err != nil || inc.Count != 2 || len(inc.Members) != 2 {
+		t.Fatalf("expected an incident with two members, got %+v, %v", inc, err)
+	}
+}
+
+// failingStore rejects every write, like an unreachable Redis.
+type failingStore struct {
+	*store.MemoryStore
+}
+
+func (failingStore) Put(ctx context.Context, rec store.Record) error {
+	return errors.New("connection refused")
+}
+
+func TestSaveDoesNotGroupAlarmsThatWereNotStored(t *testing.T) {
+	alarms := failingStore{store.NewMemoryStore()}
+	incidentStore := store.NewMemoryStore()
+	incidents, err := incident.New(alarms, incidentStore, store.NewMemoryEventLog(0), []incident.Rule{
+		{Name: "per-sender", Tag: "ALARM", Fields: []string{"sender"}, Window: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	saveWithRandomKey(alarms, store.NewMemoryEventLog(0), "disk full", "ALARM", "err", alarm.Source{IP: "10.0.0.5"}, config.Config{}, nil, incidents)
+	if stored, _ := incidentStore.List(context.Background()); len(stored) != 0 {
+		t.Fatalf("expected no incident for an alarm that failed to store, got %d", len(stored))
+	}
+}
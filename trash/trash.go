@@ -0,0 +1,258 @@
+package trash
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"logvault/store"
+)
+
+// Restore results reported per alarm key.
+const (
+	Restored = "restored"
+	NotFound = "not_found"
+	Exists   = "exists"
+	Failed   = "failed"
+)
+
+const purgePageSize = 1000
+
+// Entry is a deleted alarm as kept in the recycle bin. Alarm holds the stored
+// alarm value unchanged.
+type Entry struct {
+	ID         string          `json:"id"`
+	Tag        string          `json:"tag"`
+	ReceivedAt time.Time       `json:"received_at"`
+	DeletedBy  string          `json:"deleted_by"`
+	DeletedAt  time.Time       `json:"deleted_at"`
+	Alarm      json.RawMessage `json:"alarm"`
+}
+
+// Page is one newest-deleted-first slice of the recycle bin.
+type Page struct {
+	Entries    []Entry
+	NextCursor string
+}
+
+// Bin moves deleted alarms out of the alarm store into a separate store,
+// where they are kept for the configured retention and can be restored.
+// The bin store indexes entries by deletion time, so paging is newest
+// deleted first and expiry is a time-range query.
+type Bin struct {
+	alarms    store.AlarmStore
+	bin       store.AlarmStore
+	events    store.EventLog
+	retention time.Duration
+}
+
+// New returns a recycle bin for alarms that keeps deleted alarms in bin for
+// retention. A zero retention keeps them until they are purged.
+func New(alarms, bin store.AlarmStore, events store.EventLog, retention time.Duration) *Bin {
+	return &Bin{alarms: alarms, bin: bin, events: events, retention: retention}
+}
+
+// Move puts the given alarms in the bin, recording who deleted them, then
+// removes them from the alarm store. It returns how many alarms were removed.
+func (b *Bin) Move(ctx context.Context, by string, recs ...store.Record) (int, error) {
+	if len(recs) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	ids := make([]string, 0, len(recs))
+	for _, rec := range recs {
+		entry := Entry{
+			ID:         rec.ID,
+			Tag:        rec.Tag,
+			ReceivedAt: rec.ReceivedAt,
+			DeletedBy:  by,
+			DeletedAt:  now,
+			Alarm:      alarmValue(rec.Value),
+		}
+		value, err := json.Marshal(entry)
+		if err != nil {
+			return 0, err
+		}
+		err = b.bin.Put(ctx, store.Record{ID: rec.ID, Tag: rec.Tag, ReceivedAt: now, Value: string(value)})
+		if err != nil {
+			return 0, fmt.Errorf("failed to move %s to the recycle bin: %w", store.Key(rec.ID), err)
+		}
+		ids = append(ids, rec.ID)
+	}
+	return b.alarms.Delete(ctx, ids...)
+}
+
+// alarmValue keeps JSON alarm values as they are and wraps anything else,
+// such as plain-string values from early releases, as a JSON string.
+func alarmValue(value string) json.RawMessage {
+	if json.Valid([]byte(value)) {
+		return json.RawMessage(value)
+	}
+	quoted, _ := json.Marshal(value)
+	return quoted
+}
+
+func (e Entry) record() store.Record {
+	value := string(e.Alarm)
+	var s string
+	if json.Unmarshal(e.Alarm, &s) == nil {
+		value = s
+	}
+	return store.Record{ID: e.ID, Tag: e.Tag, ReceivedAt: e.ReceivedAt, Value: value}
+}
+
+func decodeEntry(rec store.Record) (Entry, error) {
+	var entry Entry
+	if err := json.Unmarshal([]byte(rec.Value), &entry); err != nil {
+		return entry, fmt.Errorf("invalid recycle bin entry %s: %w", rec.ID, err)
+	}
+	return entry, nil
+}
+
+// List returns one page of deleted alarms matching q, newest deleted first.
+// Since and Until in q bound the deletion time.
+func (b *Bin) List(ctx context.Context, q store.Query) (Page, error) {
+	page, err := b.bin.Query(ctx, q)
+	if err != nil {
+		return Page{}, err
+	}
+	result := Page{Entries: make([]Entry, 0, len(page.Records)), NextCursor: page.NextCursor}
+	for _, rec := range page.Records {
+		entry, err := decodeEntry(rec)
+		if err != nil {
+			log.Printf("Skipping %v", err)
+			continue
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+	return result, nil
+}
+
+// Restore moves the given alarms back to the alarm store and reports the
+// result per ID. Alarms whose ID is in use again, for example by a new
+// heartbeat alarm, are left in the bin.
+func (b *Bin) Restore(ctx context.Context, by string, ids ...string) map[string]string {
+	results := make(map[string]string, len(ids))
+	var history []store.Event
+	for _, id := range ids {
+		result, entry := b.restore(ctx, id)
+		results[id] = result
+		if result == Restored {
+			rec := entry.record()
+			history = append(history, store.Event{
+				Type:    store.EventRestore,
+				AlarmID: id,
+				Tag:     entry.Tag,
+				Actor:   by,
+				Message: fmt.Sprintf("Restored from the recycle bin, deleted by %s at %s", entry.DeletedBy, entry.DeletedAt.Format(time.RFC3339)),
+				Data:    rec.Value,
+			})
+		}
+	}
+	if len(history) == 0 {
+		return results
+	}
+	if err := b.events.Append(ctx, history...); err != nil {
+		log.Printf("Failed to record restore events: %v", err)
+	}
+	return results
+}
+
+func (b *Bin) restore(ctx context.Context, id string) (string, Entry) {
+	rec, err := b.bin.Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return NotFound, Entry{}
+	}
+	if err != nil {
+		log.Printf("Failed to read %s from the recycle bin: %v", store.Key(id), err)
+		return Failed, Entry{}
+	}
+	entry, err := decodeEntry(rec)
+	if err != nil {
+		log.Printf("Failed to restore: %v", err)
+		return Failed, Entry{}
+	}
+
+	if _, err := b.alarms.Get(ctx, id); err == nil {
+		return Exists, entry
+	} else if !errors.Is(err, store.ErrNotFound) {
+		log.Printf("Failed to check %s before restore: %v", store.Key(id), err)
+		return Failed, entry
+	}
+
+	if err := b.alarms.Put(ctx, entry.record()); err != nil {
+		log.Printf("Failed to restore %s: %v", store.Key(id), err)
+		return Failed, entry
+	}
+	if _, err := b.bin.Delete(ctx, id); err != nil {
+		log.Printf("Restored %s but failed to remove it from the recycle bin: %v", store.Key(id), err)
+	}
+	return Restored, entry
+}
+
+// Purge permanently deletes the given alarms from the bin and reports how
+// many existed.
+func (b *Bin) Purge(ctx context.Context, by string, ids ...string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	purged, err := b.bin.Delete(ctx, ids...)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("TRASH: %s purged %d alarms from the recycle bin", by, purged)
+	return purged, nil
+}
+
+// PurgeBefore permanently deletes every alarm deleted before cutoff, or every
+// alarm in the bin when cutoff is zero.
+func (b *Bin) PurgeBefore(ctx context.Context, by string, cutoff time.Time) (int, error) {
+	purged := 0
+	q := store.Query{Until: cutoff, Limit: purgePageSize}
+	for {
+		page, err := b.bin.Query(ctx, q)
+		if err != nil {
+			return purged, err
+		}
+		ids := make([]string, len(page.Records))
+		for i, rec := range page.Records {
+			ids[i] = rec.ID
+		}
+		n, err := b.Purge(ctx, by, ids...)
+		purged += n
+		if err != nil {
+			return purged, err
+		}
+		// Purged entries drop out of the index, so the next page starts over.
+		if page.NextCursor == "" {
+			return purged, nil
+		}
+	}
+}
+
+// Run purges alarms that have been in the bin longer than the retention
+// every interval. It returns immediately when retention is zero.
+func (b *Bin) Run(interval time.Duration) {
+	if b == nil || b.retention <= 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		purged, err := b.PurgeBefore(context.Background(), "retention", time.Now().Add(-b.retention))
+		if err != nil {
+			log.Printf("Recycle bin purge failed: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("TRASH: Purged %d alarms deleted more than %s ago", purged, b.retention)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package trash
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"logvault/store"
+)
+
+func newTestBin(t *testing.T) (*Bin, store.AlarmStore, store.AlarmStore, store.EventLog) {
+	t.Helper()
+	alarms := store.NewMemoryStore()
+	binStore := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	return New(alarms, binStore, events, time.Hour), alarms, binStore, events
+}
+
+func TestMoveAndRestoreKeepsAlarm(t *testing.T) {
+	ctx := context.Background()
+	bin, alarms, _, events := newTestBin(t)
+	receivedAt := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	rec := store.Record{ID: "a1", Tag: "INSIGHTS", ReceivedAt: receivedAt, Value: `{"v":2,"id":"a1","tag":"INSIGHTS"}`}
+	plain := store.Record{ID: "a2", Tag: "ALARM", ReceivedAt: receivedAt, Value: "plain text"}
+	alarms.Put(ctx, rec)
+	alarms.Put(ctx, plain)
+
+	moved, err := bin.Move(ctx, "admin", rec, plain)
+	if err != nil || moved != 2 {
+		t.Fatalf("expected 2 alarms moved, got %d, %v", moved, err)
+	}
+	if remaining, _ := alarms.List(ctx); len(remaining) != 0 {
+		t.Fatalf("expected alarms to be removed from the store, got %v", remaining)
+	}
+
+	page, err := bin.List(ctx, store.Query{})
+	if err != nil || len(page.Entries) != 2 {
+		t.Fatalf("expected 2 entries in the bin, got %+v, %v", page.Entries, err)
+	}
+	if page.Entries[0].DeletedBy != "admin" || page.Entries[0].DeletedAt.IsZero() {
+		t.Fatalf("expected deleter to be recorded, got %+v", page.Entries[0])
+	}
+
+	results := bin.Restore(ctx, "ops", "a1", "a2", "missing")
+	if results["a1"] != Restored || results["a2"] != Restored || results["missing"] != NotFound {
+		t.Fatalf("unexpected restore results %v", results)
+	}
+	got, err := alarms.Get(ctx, "a1")
+	if err != nil || got.Value != rec.Value || !got.ReceivedAt.Equal(receivedAt) || got.Tag != "INSIGHTS" {
+		t.Fatalf("expected a1 restored unchanged, got %+v, %v", got, err)
+	}
+	if got, _ := alarms.Get(ctx, "a2"); got.Value != "plain text" {
+		t.Fatalf("expected plain value to be restored as is, got %q", got.Value)
+	}
+	if page, _ := bin.List(ctx, store.Query{}); len(page.Entries) != 0 {
+		t.Fatalf("expected the bin to be empty after restore, got %+v", page.Entries)
+	}
+
+	history, _ := events.Events(ctx, store.EventQuery{})
+	if len(history.Events) != 2 || history.Events[0].Type != store.EventRestore || history.Events[0].Actor != "ops" {
+		t.Fatalf("expected restore events by ops, got %+v", history.Events)
+	}
+}
+
+func TestRestoreLeavesEntryWhenIDIsInUse(t *testing.T) {
+	ctx := context.Background()
+	bin, alarms, _, _ := newTestBin(t)
+	rec := store.Record{ID: "sender-silent:fw", Tag: "SENDER_SILENT", ReceivedAt: time.Now(), Value: `{"old":true}`}
+	alarms.Put(ctx, rec)
+	bin.Move(ctx, "admin", rec)
+	alarms.Put(ctx, store.Record{ID: "sender-silent:fw", Tag: "SENDER_SILENT", ReceivedAt: time.Now(), Value: `{"new":true}`})
+
+	if results := bin.Restore(ctx, "admin", "sender-silent:fw"); results["sender-silent:fw"] != Exists {
+		t.Fatalf("expected restore to report the ID in use, got %v", results)
+	}
+	if got, _ := alarms.Get(ctx, "sender-silent:fw"); got.Value != `{"new":true}` {
+		t.Fatalf("expected the newer alarm to be kept, got %q", got.Value)
+	}
+	if page, _ := bin.List(ctx, store.Query{}); len(page.Entries) != 1 {
+		t.Fatal("expected the entry to stay in the bin")
+	}
+}
+
+func TestPurgeBeforeRemovesOldEntries(t *testing.T) {
+	ctx := context.Background()
+	bin, _, binStore, _ := newTestBin(t)
+	now := time.Now()
+	binStore.Put(ctx, store.Record{ID: "old", ReceivedAt: now.Add(-2 * time.Hour), Value: `{"id":"old"}`})
+	binStore.Put(ctx, store.Record{ID: "new", ReceivedAt: now, Value: `{"id":"new"}`})
+
+	purged, err := bin.PurgeBefore(ctx, "retention", now.Add(-time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("expected 1 entry purged, got %d, %v", purged, err)
+	}
+	if _, err := binStore.Get(ctx, "new"); err != nil {
+		t.Fatalf("expected recent entry to be kept: %v", err)
+	}
+
+	if purged, _ := bin.PurgeBefore(ctx, "admin", time.Time{}); purged != 1 {
+		t.Fatalf("expected a zero cutoff to purge everything, got %d", purged)
+	}
+}
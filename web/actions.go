@@ -0,0 +1,150 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/notifier"
+	"logvault/store"
+)
+
+// maxActionBodyBytes bounds the optional JSON body of alarm actions.
+const maxActionBodyBytes = 64 << 10
+
+// splitAlarmPath splits /api/alarms/{key}[/{action}] into the alarm key and
+// action. Both are empty for the collection path.
+func splitAlarmPath(path string) (key, action string) {
+	rest := strings.Trim(strings.TrimPrefix(path, "/api/alarms"), "/")
+	key, action, _ = strings.Cut(rest, "/")
+	return key, action
+}
+
+// canAckAlarms reports whether the request may acknowledge alarms: bearer
+// token clients and admins always can, readonly users when
+// web.readonly_can_ack is set.
+func canAckAlarms(r *http.Request, appConfig config.Config) bool {
+	if canDeleteAlarms(r, appConfig) {
+		return true
+	}
+	session, ok := currentSession(r)
+	return ok && session.Role == roleReadOnly && appConfig.Web.ReadOnlyCanAck
+}
+
+// canApplyAction reports whether the request may perform a lifecycle action.
+// Acknowledging follows canAckAlarms; resolving, closing and reopening are
+// limited to those who may delete alarms.
+func canApplyAction(r *http.Request, a alarm.Action, appConfig config.Config) bool {
+	if a.Name == alarm.ActionAck.Name || a.Name == alarm.ActionUnack.Name {
+		return canAckAlarms(r, appConfig)
+	}
+	return canDeleteAlarms(r, appConfig)
+}
+
+// actionRequest is the optional body of an alarm action.
+type actionRequest struct {
+	Comment string `json:"comment"`
+}
+
+func decodeActionRequest(r *http.Request) (actionRequest, error) {
+	var req actionRequest
+	if r.Body == nil {
+		return req, nil
+	}
+	err := json.NewDecoder(io.LimitReader(r.Body, maxActionBodyBytes)).Decode(&req)
+	if errors.Is(err, io.EOF) {
+		return req, nil // An empty body means no comment
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	return req, err
+}
+
+// alarmAction serves POST /api/alarms/{key}/{action}, where action is one of
+// ack, unack, resolve, close or reopen.
+func alarmAction(w http.ResponseWriter, r *http.Request, alarms store.AlarmStore, events store.EventLog, appConfig config.Config) {
+	key, name := splitAlarmPath(r.URL.Path)
+	if key == "" || name == "" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	action, ok := alarm.ActionByName(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if !canApplyAction(r, action, appConfig) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	req, err := decodeActionRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	actor := requestActor(r)
+	doc, err := alarm.Change(context.Background(), alarms, events, key, action, actor, req.Comment)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Alarm not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, alarm.ErrInvalidTransition) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to %s key %s via API: %v", action.Name, store.Key(key), err)
+		http.Error(w, "Failed to update alarm", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("API: %s %s by %s, now %s", action.Name, store.Key(key), actor, doc.Status())
+	notifyStatus(doc, appConfig)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// notifyStatus sends the alarm to the external API with its new status.
+// Alarms raised during a silence or maintenance window were never announced,
+// so neither are their changes.
+func notifyStatus(doc alarm.Document, appConfig config.Config) {
+	if !appConfig.ExternalAPI.Enabled || doc.Suppressed() {
+		return
+	}
+	if rec, err := doc.Record(); err == nil {
+		go notifier.CallExternalAPI(appConfig, map[string]string{
+			"key":     store.Key(doc.ID),
+			"message": rec.Value,
+			"status":  strings.ToUpper(doc.Status()),
+		})
+	}
+}
+
+// alarmHistory serves GET /api/alarms/{key}/history, the alarm's audit trail
+// of status transitions, oldest first.
+func alarmHistory(w http.ResponseWriter, r *http.Request, alarms store.AlarmStore, key string) {
+	rec, err := alarms.Get(context.Background(), key)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Alarm not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to GET key %s via API: %v", store.Key(key), err)
+		http.Error(w, "Failed to get alarm", http.StatusInternalServerError)
+		return
+	}
+
+	history := decodeAlarm(rec).State.History
+	if history == nil {
+		history = []alarm.Transition{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/notifier"
+	"logvault/store"
+)
+
+// assignRequest is the body of POST /api/alarms/{key}/assign. An empty
+// assignee unassigns the alarm.
+type assignRequest struct {
+	Assignee string `json:"assignee"`
+}
+
+// isWebUser reports whether username is a configured web UI user.
+func isWebUser(appConfig config.Config, username string) bool {
+	for _, user := range appConfig.Web.Users {
+		if user.Username == username {
+			return true
+		}
+	}
+	return appConfig.Web.Username != "" && appConfig.Web.Username == username
+}
+
+// alarmAssign serves POST /api/alarms/{key}/assign. Admins and bearer token
+// clients may assign any web user or unassign the alarm; other users who may
+// acknowledge alarms may only claim an unassigned alarm for themselves or
+// release their own claim.
+func alarmAssign(w http.ResponseWriter, r *http.Request, alarms store.AlarmStore, events store.EventLog, key string, appConfig config.Config) {
+	var req assignRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxActionBodyBytes)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	assignee := strings.TrimSpace(req.Assignee)
+	actor := requestActor(r)
+	ctx := context.Background()
+
+	assign := alarm.Assign
+	switch {
+	case canDeleteAlarms(r, appConfig):
+		if assignee != "" && !isWebUser(appConfig, assignee) {
+			http.Error(w, "Unknown user", http.StatusBadRequest)
+			return
+		}
+	case !canAckAlarms(r, appConfig):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	case assignee != "" && assignee != actor:
+		http.Error(w, "Only admins can assign alarms to others", http.StatusForbidden)
+		return
+	default:
+		assign = alarm.AssignOwn
+	}
+
+	doc, changed, err := assign(ctx, alarms, events, key, assignee, actor)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Alarm not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, alarm.ErrClaimed) {
+		http.Error(w, "Only admins can change alarms claimed by others", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to assign key %s via API: %v", store.Key(key), err)
+		http.Error(w, "Failed to assign alarm", http.StatusInternalServerError)
+		return
+	}
+
+	if changed {
+		if assignee == "" {
+			log.Printf("API: %s unassigned %s", actor, store.Key(key))
+		} else {
+			log.Printf("API: %s assigned %s to %s", actor, store.Key(key), assignee)
+			notifyAssigned(doc, appConfig)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// notifyAssigned tells the external API who took the alarm, unless it was
+// raised during a silence or maintenance window.
+func notifyAssigned(doc alarm.Document, appConfig config.Config) {
+	if !appConfig.ExternalAPI.Enabled || doc.Suppressed() {
+		return
+	}
+	if rec, err := doc.Record(); err == nil {
+		go notifier.CallExternalAPI(appConfig, map[string]string{
+			"key":     store.Key(doc.ID),
+			"message": rec.Value,
+			"status":  "ASSIGNED",
+		})
+	}
+}
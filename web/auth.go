@@ -293,16 +293,19 @@ func currentSession(r *http.Request) (sessionData, bool) {
 	return session, true
 }
 
-func sessionInfoHandler(w http.ResponseWriter, r *http.Request) {
-	session, ok := currentSession(r)
-	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
+func sessionInfoHandler(appConfig config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, ok := currentSession(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"username": session.Username,
-		"role":     session.Role,
-	})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"username": session.Username,
+			"role":     session.Role,
+			"can_ack":  canAckAlarms(r, appConfig),
+		})
+	}
 }
@@ -0,0 +1,258 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/notifier"
+	"logvault/store"
+	"logvault/trash"
+)
+
+// maxBulkAlarms bounds how many alarms one bulk request may change.
+const maxBulkAlarms = 5000
+
+// Per-key results of a bulk request.
+const (
+	bulkOK        = "ok"
+	bulkUnchanged = "unchanged"
+	bulkNotFound  = "not_found"
+	bulkConflict  = "conflict"
+	bulkFailed    = "failed"
+)
+
+// bulkRequest is the body of POST /api/alarms/bulk. It selects alarms by
+// keys or by a filter expression, not both. Comment applies to lifecycle
+// actions, Assignee to assign, and Add and Remove to tag.
+type bulkRequest struct {
+	Action   string   `json:"action"`
+	Keys     []string `json:"keys"`
+	Filter   string   `json:"filter"`
+	Comment  string   `json:"comment"`
+	Assignee string   `json:"assignee"`
+	Add      []string `json:"add"`
+	Remove   []string `json:"remove"`
+}
+
+type bulkResponse struct {
+	Matched int               `json:"matched"`
+	Results map[string]string `json:"results"`
+}
+
+// alarmBulk serves POST /api/alarms/bulk. Action is a lifecycle action
+// (ack, unack, resolve, close, reopen), assign, tag or delete, with the same
+// permissions as on a single alarm. The response has a result per key.
+func alarmBulk(w http.ResponseWriter, r *http.Request, alarms store.AlarmStore, events store.EventLog, bin *trash.Bin, appConfig config.Config) {
+	var req bulkRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxActionBodyBytes*16)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Action = strings.ToLower(strings.TrimSpace(req.Action))
+	req.Assignee = strings.TrimSpace(req.Assignee)
+	req.Comment = strings.TrimSpace(req.Comment)
+	actor := requestActor(r)
+
+	lifecycle, isLifecycle := alarm.ActionByName(req.Action)
+	assign := alarm.Assign
+	switch {
+	case isLifecycle:
+		if !canApplyAction(r, lifecycle, appConfig) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	case req.Action == "assign":
+		if canDeleteAlarms(r, appConfig) {
+			if req.Assignee != "" && !isWebUser(appConfig, req.Assignee) {
+				http.Error(w, "Unknown user", http.StatusBadRequest)
+				return
+			}
+		} else if !canAckAlarms(r, appConfig) || (req.Assignee != "" && req.Assignee != actor) {
+			http.Error(w, "Only admins can assign alarms to others", http.StatusForbidden)
+			return
+		} else {
+			assign = alarm.AssignOwn
+		}
+	case req.Action == "tag":
+		if !canAckAlarms(r, appConfig) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if len(req.Add) == 0 && len(req.Remove) == 0 {
+			http.Error(w, "Either add or remove is required", http.StatusBadRequest)
+			return
+		}
+	case req.Action == "delete":
+		if !canDeleteAlarms(r, appConfig) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	keys, err := bulkKeys(ctx, alarms, req)
+	if errors.Is(err, alarm.ErrInvalidFilter) || errors.Is(err, errBulkSelection) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to select alarms for bulk %s: %v", req.Action, err)
+		http.Error(w, "Failed to select alarms", http.StatusInternalServerError)
+		return
+	}
+
+	resp := bulkResponse{Matched: len(keys), Results: make(map[string]string, len(keys))}
+	switch {
+	case isLifecycle:
+		for _, key := range keys {
+			doc, err := alarm.Change(ctx, alarms, events, key, lifecycle, actor, req.Comment)
+			resp.Results[key] = bulkResult(key, err, true)
+			if err == nil {
+				notifyStatus(doc, appConfig)
+			}
+		}
+	case req.Action == "assign":
+		for _, key := range keys {
+			doc, changed, err := assign(ctx, alarms, events, key, req.Assignee, actor)
+			resp.Results[key] = bulkResult(key, err, changed)
+			if changed && req.Assignee != "" {
+				notifyAssigned(doc, appConfig)
+			}
+		}
+	case req.Action == "tag":
+		for _, key := range keys {
+			_, changed, err := alarm.Label(ctx, alarms, events, key, req.Add, req.Remove, actor)
+			resp.Results[key] = bulkResult(key, err, changed)
+		}
+	case req.Action == "delete":
+		bulkDelete(ctx, alarms, events, bin, actor, keys, resp.Results, appConfig)
+	}
+
+	changed := 0
+	for _, result := range resp.Results {
+		if result == bulkOK {
+			changed++
+		}
+	}
+	log.Printf("API: %s bulk %s changed %d of %d alarms", actor, req.Action, changed, len(keys))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+var errBulkSelection = errors.New("either keys or filter is required")
+
+// bulkKeys returns the keys a bulk request selects: its keys, or every
+// alarm matching its filter. Only a tag= term narrows the store query; any
+// other filter reads and decodes every stored alarm, one page at a time.
+func bulkKeys(ctx context.Context, alarms store.AlarmStore, req bulkRequest) ([]string, error) {
+	filter := strings.TrimSpace(req.Filter)
+	switch {
+	case len(req.Keys) > 0 && filter != "", len(req.Keys) == 0 && filter == "":
+		return nil, errBulkSelection
+	case len(req.Keys) > maxBulkAlarms:
+		return nil, fmt.Errorf("%w: at most %d keys", errBulkSelection, maxBulkAlarms)
+	case len(req.Keys) > 0:
+		return req.Keys, nil
+	}
+
+	f, err := alarm.ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	q := store.Query{Tag: f.Tag(), Limit: maxAlarmPageSize}
+	for {
+		page, err := alarms.Query(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range page.Records {
+			if !f.Matches(decodeAlarm(rec)) {
+				continue
+			}
+			if len(keys) == maxBulkAlarms {
+				return nil, fmt.Errorf("%w: the filter matches more than %d alarms", errBulkSelection, maxBulkAlarms)
+			}
+			keys = append(keys, rec.ID)
+		}
+		if page.NextCursor == "" {
+			return keys, nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+// bulkResult maps the outcome of changing one alarm to its result.
+func bulkResult(key string, err error, changed bool) string {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return bulkNotFound
+	case errors.Is(err, alarm.ErrInvalidTransition), errors.Is(err, alarm.ErrClaimed):
+		return bulkConflict
+	case err != nil:
+		log.Printf("Failed to update key %s in bulk: %v", store.Key(key), err)
+		return bulkFailed
+	case !changed:
+		return bulkUnchanged
+	default:
+		return bulkOK
+	}
+}
+
+// bulkDelete removes the alarms like a single delete, recording each in the
+// event history and clearing those still active with the external API.
+func bulkDelete(ctx context.Context, alarms store.AlarmStore, events store.EventLog, bin *trash.Bin, actor string, keys []string, results map[string]string, appConfig config.Config) {
+	var found []store.Record
+	for _, key := range keys {
+		rec, err := alarms.Get(ctx, key)
+		results[key] = bulkResult(key, err, true)
+		if err == nil {
+			found = append(found, rec)
+		}
+	}
+	if len(found) == 0 {
+		return
+	}
+
+	if _, err := removeAlarms(ctx, alarms, bin, actor, found...); err != nil {
+		log.Printf("Failed to delete alarms in bulk: %v", err)
+		for _, rec := range found {
+			results[rec.ID] = bulkFailed
+		}
+		return
+	}
+
+	history := make([]store.Event, 0, len(found))
+	for _, rec := range found {
+		history = append(history, store.Event{
+			Type:    store.EventDelete,
+			AlarmID: rec.ID,
+			Tag:     rec.Tag,
+			Actor:   actor,
+			Message: "Deleted in bulk",
+			Data:    rec.Value,
+		})
+		if appConfig.ExternalAPI.Enabled && clearsOnDelete(rec) {
+			go notifier.CallExternalAPI(appConfig, map[string]string{
+				"key":     store.Key(rec.ID),
+				"message": fmt.Sprintf("Alarm cleared for %s via web UI", rec.ID),
+				"status":  "CLEAR",
+			})
+		}
+	}
+	if err := events.Append(ctx, history...); err != nil {
+		log.Printf("Failed to record delete events: %v", err)
+	}
+}
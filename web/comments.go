@@ -0,0 +1,96 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/store"
+)
+
+// maxCommentLength bounds a single comment, in characters.
+const maxCommentLength = 4000
+
+// commentRequest is the body of POST /api/alarms/{key}/comments.
+type commentRequest struct {
+	Text string `json:"text"`
+}
+
+// alarmComments serves GET and POST /api/alarms/{key}/comments. Anyone who
+// may view alarms can read the thread; adding a comment follows canAckAlarms.
+func alarmComments(w http.ResponseWriter, r *http.Request, alarms store.AlarmStore, events store.EventLog, key string, appConfig config.Config) {
+	switch r.Method {
+	case http.MethodGet:
+		listComments(w, alarms, key)
+	case http.MethodPost:
+		addComment(w, r, alarms, events, key, appConfig)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func listComments(w http.ResponseWriter, alarms store.AlarmStore, key string) {
+	rec, err := alarms.Get(context.Background(), key)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Alarm not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to GET key %s via API: %v", store.Key(key), err)
+		http.Error(w, "Failed to get alarm", http.StatusInternalServerError)
+		return
+	}
+
+	comments := decodeAlarm(rec).Comments
+	if comments == nil {
+		comments = []alarm.Comment{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comments)
+}
+
+func addComment(w http.ResponseWriter, r *http.Request, alarms store.AlarmStore, events store.EventLog, key string, appConfig config.Config) {
+	if !canAckAlarms(r, appConfig) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req commentRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxActionBodyBytes)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		http.Error(w, "Comment text is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(text) > maxCommentLength {
+		http.Error(w, "Comment is too long", http.StatusBadRequest)
+		return
+	}
+
+	actor := requestActor(r)
+	comment, err := alarm.AddComment(context.Background(), alarms, events, key, actor, text)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Alarm not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to comment on key %s via API: %v", store.Key(key), err)
+		http.Error(w, "Failed to add comment", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("API: %s commented on %s", actor, store.Key(key))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
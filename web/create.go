@@ -0,0 +1,174 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/incident"
+	"logvault/notifier"
+	"logvault/silence"
+	"logvault/store"
+)
+
+// Limits on manually created alarms.
+const (
+	maxManualFields     = 50
+	maxManualFieldKey   = 64
+	maxManualFieldValue = 4096
+)
+
+var manualTagPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// createRequest is the body of POST /api/alarms. Message is stored as the
+// message field; Source optionally names the sender the alarm is about, so
+// silences and maintenance windows for it apply.
+type createRequest struct {
+	Tag      string            `json:"tag"`
+	Severity string            `json:"severity"`
+	Message  string            `json:"message"`
+	Fields   map[string]string `json:"fields"`
+	Source   alarm.Source      `json:"source"`
+}
+
+// validate normalizes the request and checks it describes a storable alarm.
+func (req *createRequest) validate() error {
+	req.Tag = strings.TrimSpace(req.Tag)
+	if !manualTagPattern.MatchString(req.Tag) {
+		return fmt.Errorf("tag is required and may only contain letters, digits, '.', '_' and '-' (at most 64)")
+	}
+
+	req.Severity = strings.ToLower(strings.TrimSpace(req.Severity))
+	if req.Severity != "" && !containsString(alarm.Severities, req.Severity) {
+		return fmt.Errorf("severity must be one of %s", strings.Join(alarm.Severities, ", "))
+	}
+
+	fields := make(map[string]string, len(req.Fields)+1)
+	for k, v := range req.Fields {
+		k = strings.TrimSpace(k)
+		if k == "" || len(k) > maxManualFieldKey {
+			return fmt.Errorf("field names must be 1 to %d characters", maxManualFieldKey)
+		}
+		fields[k] = v
+	}
+	if msg := strings.TrimSpace(req.Message); msg != "" {
+		fields["message"] = msg
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("a message or at least one field is required")
+	}
+	if len(fields) > maxManualFields {
+		return fmt.Errorf("at most %d fields are allowed", maxManualFields)
+	}
+	for k, v := range fields {
+		if len(v) > maxManualFieldValue {
+			return fmt.Errorf("field %q is longer than %d characters", k, maxManualFieldValue)
+		}
+	}
+	req.Fields = fields
+
+	req.Source.IP = strings.TrimSpace(req.Source.IP)
+	req.Source.Hostname = strings.TrimSpace(req.Source.Hostname)
+	if req.Source.IP != "" && net.ParseIP(req.Source.IP) == nil {
+		return fmt.Errorf("source ip %q is not a valid IP address", req.Source.IP)
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// createAlarm serves POST /api/alarms, which raises an alarm by hand, e.g.
+// for a phone call or an external observation. Only admins and bearer token
+// clients may create alarms, as they are notified like syslog alarms. The
+// alarm goes through silences, incident grouping and the notifier rules like
+// one received over syslog.
+func createAlarm(w http.ResponseWriter, r *http.Request, alarms store.AlarmStore, events store.EventLog, silences *silence.Silencer, incidents *incident.Grouper, appConfig config.Config) {
+	if !canDeleteAlarms(r, appConfig) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req createRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxActionBodyBytes*4)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		log.Printf("Failed to generate random key: %v", err)
+		http.Error(w, "Failed to create alarm", http.StatusInternalServerError)
+		return
+	}
+
+	actor := requestActor(r)
+	doc := alarm.New(hex.EncodeToString(randomBytes), req.Tag, time.Now())
+	doc.Severity = req.Severity
+	doc.Source = req.Source
+	doc.Fields = req.Fields
+	doc.CreatedBy = actor
+	silenced := silences.Suppress(&doc)
+
+	ctx := context.Background()
+	rec, err := doc.Record()
+	if err != nil {
+		log.Printf("Failed to marshal manual alarm: %v", err)
+		http.Error(w, "Failed to create alarm", http.StatusInternalServerError)
+		return
+	}
+	if err := alarms.Put(ctx, rec); err != nil {
+		log.Printf("Failed to SET key %s: %v", store.Key(doc.ID), err)
+		http.Error(w, "Failed to create alarm", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("API: %s created %s alarm %s", actor, doc.Tag, store.Key(doc.ID))
+
+	if err := events.Append(ctx, store.Event{
+		Type:    store.EventIngest,
+		AlarmID: rec.ID,
+		Tag:     rec.Tag,
+		Actor:   actor,
+		Message: "Created manually",
+		Data:    rec.Value,
+	}); err != nil {
+		log.Printf("Failed to record ingest event for key %s: %v", store.Key(rec.ID), err)
+	}
+
+	notify := !silenced && appConfig.ExternalAPI.Enabled && notifier.ShouldTrigger(doc.Tag, appConfig.ExternalAPI.TriggerTags)
+	inc, first, err := incidents.Group(ctx, &doc, notify)
+	if err != nil {
+		log.Printf("Failed to group %s alarm %s into an incident: %v", doc.Tag, store.Key(doc.ID), err)
+	}
+	switch {
+	case first:
+		incident.Notify(appConfig, *inc, incident.StatusNotified)
+	case inc == nil && notify:
+		go notifier.CallExternalAPI(appConfig, map[string]string{"key": store.Key(doc.ID), "message": rec.Value, "status": doc.Tag})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(doc)
+}
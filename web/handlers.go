@@ -3,21 +3,28 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"logvault/alarm"
 	"logvault/config"
+	"logvault/incident"
 	"logvault/notifier"
-	"logvault/redis"
+	"logvault/silence"
+	"logvault/spool"
+	"logvault/store"
+	"logvault/syslog"
+	"logvault/trash"
 )
 
-const alarmPrefix = "alarm:"
-
-func serveHome(rdb *redis.RedisClient) http.HandlerFunc {
+func serveHome() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/" {
 			http.NotFound(w, r)
@@ -44,22 +51,50 @@ func serveHome(rdb *redis.RedisClient) http.HandlerFunc {
 	}
 }
 
-func alarmsHandler(rdb *redis.RedisClient, appConfig config.Config) http.HandlerFunc {
+func alarmsHandler(alarms store.AlarmStore, events store.EventLog, bin *trash.Bin, silences *silence.Silencer, incidents *incident.Grouper, appConfig config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			getAlarms(w, r, rdb)
+			if key, action := splitAlarmPath(r.URL.Path); key != "" {
+				switch action {
+				case "history":
+					alarmHistory(w, r, alarms, key)
+				case "comments":
+					alarmComments(w, r, alarms, events, key, appConfig)
+				default:
+					http.NotFound(w, r)
+				}
+				return
+			}
+			getAlarms(w, r, alarms)
+		case http.MethodPost:
+			switch key, action := splitAlarmPath(r.URL.Path); {
+			case key == "":
+				createAlarm(w, r, alarms, events, silences, incidents, appConfig)
+			case key != "" && action == "comments":
+				alarmComments(w, r, alarms, events, key, appConfig)
+			case key != "" && action == "assign":
+				alarmAssign(w, r, alarms, events, key, appConfig)
+			case key == "bulk" && action == "":
+				alarmBulk(w, r, alarms, events, bin, appConfig)
+			default:
+				alarmAction(w, r, alarms, events, appConfig)
+			}
 		case http.MethodDelete:
 			if !canDeleteAlarms(r, appConfig) {
 				http.Error(w, "Forbidden", http.StatusForbidden)
 				return
 			}
+			if _, action := splitAlarmPath(r.URL.Path); action != "" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
 			// If the path is just /api/alarms, delete all.
 			// Otherwise, it's /api/alarms/{key}, so delete one.
 			if r.URL.Path == "/api/alarms" || r.URL.Path == "/api/alarms/" {
-				deleteAllAlarms(w, r, rdb, appConfig)
+				deleteAllAlarms(w, r, alarms, events, bin, appConfig)
 			} else {
-				deleteAlarm(w, r, rdb, appConfig)
+				deleteAlarm(w, r, alarms, events, bin, appConfig)
 			}
 		default:
 			w.WriteHeader(http.StatusMethodNotAllowed)
@@ -67,6 +102,15 @@ func alarmsHandler(rdb *redis.RedisClient, appConfig config.Config) http.Handler
 	}
 }
 
+// requestActor names who made a request for the event history: the session
+// username, or "api" for bearer token clients.
+func requestActor(r *http.Request) string {
+	if session, ok := currentSession(r); ok {
+		return session.Username
+	}
+	return "api"
+}
+
 func canDeleteAlarms(r *http.Request, appConfig config.Config) bool {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader != "" {
@@ -78,33 +122,77 @@ func canDeleteAlarms(r *http.Request, appConfig config.Config) bool {
 	return ok && session.Role == roleAdmin
 }
 
-func deleteAllAlarms(w http.ResponseWriter, r *http.Request, rdb *redis.RedisClient, appConfig config.Config) {
+// removeAlarms moves alarms to the recycle bin, or deletes them outright
+// when the bin is disabled.
+func removeAlarms(ctx context.Context, alarms store.AlarmStore, bin *trash.Bin, actor string, recs ...store.Record) (int, error) {
+	var found []store.Record
+	for _, rec := range recs {
+		if rec.ID != "" {
+			found = append(found, rec)
+		}
+	}
+	if bin != nil {
+		return bin.Move(ctx, actor, found...)
+	}
+	ids := make([]string, len(found))
+	for i, rec := range found {
+		ids[i] = rec.ID
+	}
+	return alarms.Delete(ctx, ids...)
+}
+
+// clearsOnDelete reports whether deleting rec is sent to the external API as
+// a CLEAR. Resolved and closed alarms were already reported when they changed
+// status, and silenced alarms or those raised during a maintenance window
+// were never announced.
+func clearsOnDelete(rec store.Record) bool {
+	doc := decodeAlarm(rec)
+	return doc.Active() && !doc.Suppressed()
+}
+
+func deleteAllAlarms(w http.ResponseWriter, r *http.Request, alarms store.AlarmStore, events store.EventLog, bin *trash.Bin, appConfig config.Config) {
 	ctx := context.Background()
-	keys, err := rdb.GetKeysByPattern(ctx, alarmPrefix+"*")
+	all, err := alarms.List(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get keys from Redis", http.StatusInternalServerError)
+		http.Error(w, "Failed to list alarms", http.StatusInternalServerError)
 		return
 	}
 
-	if len(keys) == 0 {
+	if len(all) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	// The underlying go-redis Del method accepts multiple keys
-	if err := rdb.Del(keys...); err != nil {
-		log.Printf("Failed to DEL all alarm keys via API: %v", err)
-		http.Error(w, "Failed to delete keys from Redis", http.StatusInternalServerError)
+	history := make([]store.Event, 0, len(all))
+	actor := requestActor(r)
+	for _, rec := range all {
+		history = append(history, store.Event{
+			Type:    store.EventDelete,
+			AlarmID: rec.ID,
+			Tag:     rec.Tag,
+			Actor:   actor,
+			Message: "Deleted with all alarms",
+			Data:    rec.Value,
+		})
+	}
+
+	deleted, err := removeAlarms(ctx, alarms, bin, actor, all...)
+	if err != nil {
+		log.Printf("Failed to delete all alarms via API: %v", err)
+		http.Error(w, "Failed to delete alarms", http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("API: Deleted %d alarm keys", len(keys))
+	log.Printf("API: Deleted %d alarm keys", deleted)
+	if err := events.Append(ctx, history...); err != nil {
+		log.Printf("Failed to record delete events: %v", err)
+	}
 
 	// Call external API if enabled
 	if appConfig.ExternalAPI.Enabled {
 		go notifier.CallExternalAPI(appConfig, map[string]string{
 			"key":     "ALL_ALARMS",
-			"message": fmt.Sprintf("Deleted %d alarms via web UI", len(keys)),
+			"message": fmt.Sprintf("Deleted %d alarms via web UI", deleted),
 			"status":  "CLEAR",
 		})
 	}
@@ -112,60 +200,177 @@ func deleteAllAlarms(w http.ResponseWriter, r *http.Request, rdb *redis.RedisCli
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func getAlarms(w http.ResponseWriter, r *http.Request, rdb *redis.RedisClient) {
+const defaultAlarmPageSize = 100
+const maxAlarmPageSize = 1000
+
+// alarmItem is one entry of a paged /api/alarms response.
+type alarmItem struct {
+	Key        string         `json:"key"`
+	Tag        string         `json:"tag"`
+	ReceivedAt time.Time      `json:"received_at"`
+	Alarm      alarm.Document `json:"alarm"`
+}
+
+type alarmPage struct {
+	Alarms     []alarmItem `json:"alarms"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// decodeAlarm returns the stored alarm document, upgrading values written
+// by earlier releases.
+func decodeAlarm(rec store.Record) alarm.Document {
+	doc, _ := alarm.Decode(rec)
+	return doc
+}
+
+func getAlarms(w http.ResponseWriter, r *http.Request, alarms store.AlarmStore) {
+	params := r.URL.Query()
+	if params.Has("limit") || params.Has("cursor") || params.Has("since") || params.Has("until") || params.Has("tag") || params.Has("assignee") || params.Has("incident") {
+		getAlarmPage(w, r, alarms)
+		return
+	}
+
 	ctx := context.Background()
-	keys, err := rdb.GetKeysByPattern(ctx, alarmPrefix+"*")
+	stored, err := alarms.List(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get keys from Redis", http.StatusInternalServerError)
+		http.Error(w, "Failed to list alarms", http.StatusInternalServerError)
 		return
 	}
 
-	if len(keys) == 0 {
+	if len(stored) == 0 {
 		w.Header().Set("Content-Type", "application/json")
 		fmt.Fprintln(w, "{}") // Return empty JSON object
 		return
 	}
 
-	alarms := make(map[string]interface{})
-	for _, key := range keys {
-		val, err := rdb.Get(key)
-		if err != nil {
-			log.Printf("Failed to get value for key %s: %v", key, err)
-			continue
+	result := make(map[string]alarm.Document, len(stored))
+	for _, rec := range stored {
+		result[rec.ID] = decodeAlarm(rec)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// getAlarmPage serves newest-first pages of alarms. Supported query
+// parameters are limit, cursor (from a previous next_cursor), tag, assignee
+// ("me" for the signed-in user), incident, and since and until as RFC 3339
+// timestamps or Unix milliseconds.
+func getAlarmPage(w http.ResponseWriter, r *http.Request, alarms store.AlarmStore) {
+	params := r.URL.Query()
+	q := store.Query{
+		Tag:    params.Get("tag"),
+		Cursor: params.Get("cursor"),
+		Limit:  defaultAlarmPageSize,
+	}
+
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
 		}
-		cleanKey := strings.TrimPrefix(key, alarmPrefix)
+		if limit > maxAlarmPageSize {
+			limit = maxAlarmPageSize
+		}
+		q.Limit = limit
+	}
 
-		var v interface{}
-		// Try to unmarshal the value as JSON
-		if err := json.Unmarshal([]byte(val), &v); err == nil {
-			alarms[cleanKey] = v // It's JSON, store the parsed object
-		} else {
-			alarms[cleanKey] = val // It's not JSON, store as a plain string
+	if assignee := params.Get("assignee"); assignee != "" {
+		if assignee == "me" {
+			assignee = requestActor(r)
 		}
+		q.Fields = map[string]string{"assignee": assignee}
+	}
+	if id := params.Get("incident"); id != "" {
+		if q.Fields == nil {
+			q.Fields = make(map[string]string)
+		}
+		q.Fields["incident"] = id
+	}
+
+	var err error
+	if q.Since, err = parseTimeParam(params.Get("since")); err != nil {
+		http.Error(w, "Invalid since", http.StatusBadRequest)
+		return
+	}
+	if q.Until, err = parseTimeParam(params.Get("until")); err != nil {
+		http.Error(w, "Invalid until", http.StatusBadRequest)
+		return
+	}
+
+	page, err := alarms.Query(context.Background(), q)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to query alarms: %v", err)
+		http.Error(w, "Failed to list alarms", http.StatusInternalServerError)
+		return
+	}
+
+	result := alarmPage{Alarms: make([]alarmItem, 0, len(page.Records)), NextCursor: page.NextCursor}
+	for _, rec := range page.Records {
+		result.Alarms = append(result.Alarms, alarmItem{
+			Key:        rec.ID,
+			Tag:        rec.Tag,
+			ReceivedAt: rec.ReceivedAt,
+			Alarm:      decodeAlarm(rec),
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(alarms)
+	json.NewEncoder(w).Encode(result)
+}
+
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Parse(time.RFC3339, v)
 }
 
-func deleteAlarm(w http.ResponseWriter, r *http.Request, rdb *redis.RedisClient, appConfig config.Config) {
+func deleteAlarm(w http.ResponseWriter, r *http.Request, alarms store.AlarmStore, events store.EventLog, bin *trash.Bin, appConfig config.Config) {
 	key := strings.TrimPrefix(r.URL.Path, "/api/alarms/")
 	if key == "" {
 		http.Error(w, "Key is missing", http.StatusBadRequest)
 		return
 	}
 
-	fullKey := alarmPrefix + key
-	if err := rdb.Del(fullKey); err != nil {
+	ctx := context.Background()
+	fullKey := store.Key(key)
+	rec, err := alarms.Get(ctx, key)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("Failed to GET key %s via API: %v", fullKey, err)
+		http.Error(w, "Failed to delete alarm", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := removeAlarms(ctx, alarms, bin, requestActor(r), rec); err != nil {
 		log.Printf("Failed to DEL key %s via API: %v", fullKey, err)
-		http.Error(w, "Failed to delete key from Redis", http.StatusInternalServerError)
+		http.Error(w, "Failed to delete alarm", http.StatusInternalServerError)
 		return
 	}
 
 	log.Printf("API: Deleted key %s", fullKey)
+	if rec.ID != "" {
+		err := events.Append(ctx, store.Event{
+			Type:    store.EventDelete,
+			AlarmID: rec.ID,
+			Tag:     rec.Tag,
+			Actor:   requestActor(r),
+			Data:    rec.Value,
+		})
+		if err != nil {
+			log.Printf("Failed to record delete event for key %s: %v", fullKey, err)
+		}
+	}
 
-	// Call external API if enabled
-	if appConfig.ExternalAPI.Enabled {
+	if appConfig.ExternalAPI.Enabled && (rec.ID == "" || clearsOnDelete(rec)) {
 		go notifier.CallExternalAPI(appConfig, map[string]string{
 			"key":     fullKey,
 			"message": fmt.Sprintf("Alarm cleared for %s via web UI", key),
@@ -176,24 +381,120 @@ func deleteAlarm(w http.ResponseWriter, r *http.Request, rdb *redis.RedisClient,
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func getAllRedisDataHandler(rdb *redis.RedisClient) http.HandlerFunc {
+type eventPage struct {
+	Events     []store.Event `json:"events"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// eventsHandler serves the alarm history newest first. Supported query
+// parameters are limit, cursor (from a previous next_cursor), alarm, and
+// since and until as RFC 3339 timestamps or Unix milliseconds.
+func eventsHandler(events store.EventLog) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		keys, err := rdb.GetAllKeys(ctx)
-		if err != nil {
-			http.Error(w, "Failed to get Redis keys: "+err.Error(), http.StatusInternalServerError)
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
 
-		data := make(map[string]string)
-		for _, key := range keys {
-			val, err := rdb.Get(key)
-			if err != nil {
-				// Log the error but continue to get other keys
-				log.Printf("Failed to get value for key %s: %v", key, err)
-				continue
+		params := r.URL.Query()
+		q := store.EventQuery{
+			AlarmID: params.Get("alarm"),
+			Before:  params.Get("cursor"),
+			Limit:   defaultAlarmPageSize,
+		}
+		if v := params.Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil || limit <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+			if limit > maxAlarmPageSize {
+				limit = maxAlarmPageSize
 			}
-			data[key] = val
+			q.Limit = limit
+		}
+
+		var err error
+		if q.Since, err = parseTimeParam(params.Get("since")); err != nil {
+			http.Error(w, "Invalid since", http.StatusBadRequest)
+			return
+		}
+		if q.Until, err = parseTimeParam(params.Get("until")); err != nil {
+			http.Error(w, "Invalid until", http.StatusBadRequest)
+			return
+		}
+
+		page, err := events.Events(context.Background(), q)
+		if errors.Is(err, store.ErrInvalidCursor) {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to read event history: %v", err)
+			http.Error(w, "Failed to read event history", http.StatusInternalServerError)
+			return
+		}
+
+		result := eventPage{Events: page.Events, NextCursor: page.NextCursor}
+		if result.Events == nil {
+			result.Events = []store.Event{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// healthStatus is the /api/health response. Status is "ok", or "degraded"
+// while writes are being spooled to local disk.
+type healthStatus struct {
+	Status        string                   `json:"status"`
+	Storage       string                   `json:"storage"`
+	Degraded      bool                     `json:"degraded"`
+	DegradedSince *time.Time               `json:"degraded_since,omitempty"`
+	Spooled       int                      `json:"spooled"`
+	TagRejections syslog.TagRejectionStats `json:"tag_rejections"`
+}
+
+// healthHandler reports whether the alarm store is reachable. It does not
+// require authentication so load balancers and monitors can poll it; the
+// per-sender tag rejection counts are only included for admins and bearer
+// token clients, as they name sender IPs.
+func healthHandler(spooler *spool.Spool, appConfig config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		st := spooler.Status()
+		result := healthStatus{
+			Status:   "ok",
+			Storage:  strings.ToLower(appConfig.Storage.Backend),
+			Degraded: st.Degraded,
+			Spooled:  st.Pending,
+		}
+		result.TagRejections = syslog.TagRejections()
+		if !canDeleteAlarms(r, appConfig) {
+			result.TagRejections.BySource = nil
+		}
+		if result.Storage == "" {
+			result.Storage = "redis"
+		}
+		if st.Degraded {
+			result.Status = "degraded"
+			result.DegradedSince = &st.DegradedSince
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+func getAllRedisDataHandler(alarms store.AlarmStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := alarms.Dump(context.Background())
+		if err != nil {
+			http.Error(w, "Failed to get stored data: "+err.Error(), http.StatusInternalServerError)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
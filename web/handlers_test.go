@@ -1,12 +1,21 @@
 package web
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"logvault/alarm"
 	"logvault/config"
+	"logvault/incident"
+	"logvault/maintenance"
+	"logvault/silence"
+	"logvault/store"
+	"logvault/trash"
 )
 
 func TestCanDeleteAlarmsAllowsAdminSession(t *testing.T) {
@@ -55,6 +64,690 @@ func TestCanDeleteAlarmsAllowsBearerToken(t *testing.T) {
 	}
 }
 
+func TestAlarmsHandlerListsStoredAlarms(t *testing.T) {
+	alarms := store.NewMemoryStore()
+	alarms.Put(context.Background(), store.Record{ID: "abc", Tag: "ALARM", ReceivedAt: time.Now(), Value: `{"tag":"ALARM","message":"disk full"}`})
+	alarms.Put(context.Background(), store.Record{ID: "raw", ReceivedAt: time.Now(), Value: `plain text`})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/alarms", nil)
+	rr := httptest.NewRecorder()
+	alarmsHandler(alarms, store.NewMemoryEventLog(0), nil, nil, nil, config.Config{})(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	var body map[string]alarm.Document
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if doc := body["abc"]; doc.Version != alarm.SchemaVersion || doc.Tag != "ALARM" || doc.Raw != "disk full" {
+		t.Fatalf("expected legacy JSON alarm to be upgraded, got %+v", doc)
+	}
+	if doc := body["raw"]; doc.Raw != "plain text" || doc.State.Status != alarm.StatusOpen {
+		t.Fatalf("expected non-JSON alarm to be upgraded with its raw text, got %+v", doc)
+	}
+}
+
+func TestAlarmsHandlerDeletesSingleAlarm(t *testing.T) {
+	cfg := config.Config{}
+	cfg.API.BearerToken = "api-token"
+	alarms := store.NewMemoryStore()
+	alarms.Put(context.Background(), store.Record{ID: "abc", Tag: "ALARM", Value: `{"tag":"ALARM"}`})
+	alarms.Put(context.Background(), store.Record{ID: "def", Tag: "ALARM", Value: `{"tag":"ALARM"}`})
+
+	events := store.NewMemoryEventLog(0)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/alarms/abc", nil)
+	req.Header.Set("Authorization", "Bearer api-token")
+	rr := httptest.NewRecorder()
+	alarmsHandler(alarms, events, nil, nil, nil, cfg)(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+	remaining, _ := alarms.List(context.Background())
+	if len(remaining) != 1 || remaining[0].ID != "def" {
+		t.Fatalf("expected only abc to be deleted, got %v", remaining)
+	}
+
+	history, _ := events.Events(context.Background(), store.EventQuery{})
+	if len(history.Events) != 1 || history.Events[0].Type != store.EventDelete || history.Events[0].Actor != "api" {
+		t.Fatalf("expected one delete event by api, got %+v", history.Events)
+	}
+}
+
+func TestAlarmsHandlerPagesNewestFirst(t *testing.T) {
+	alarms := store.NewMemoryStore()
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, id := range []string{"a", "b", "c"} {
+		alarms.Put(context.Background(), store.Record{ID: id, Tag: "ALARM", ReceivedAt: base.Add(time.Duration(i) * time.Minute), Value: `{"tag":"ALARM"}`})
+	}
+
+	fetch := func(url string) alarmPage {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		rr := httptest.NewRecorder()
+		alarmsHandler(alarms, store.NewMemoryEventLog(0), nil, nil, nil, config.Config{})(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200 for %s, got %d", url, rr.Code)
+		}
+		var page alarmPage
+		if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+			t.Fatalf("failed to decode page: %v", err)
+		}
+		return page
+	}
+
+	first := fetch("/api/alarms?limit=2")
+	if len(first.Alarms) != 2 || first.Alarms[0].Key != "c" || first.Alarms[1].Key != "b" || first.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+
+	second := fetch("/api/alarms?limit=2&cursor=" + first.NextCursor)
+	if len(second.Alarms) != 1 || second.Alarms[0].Key != "a" || second.NextCursor != "" {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+}
+
+func TestHealthHandlerReportsOKWithoutSpool(t *testing.T) {
+	cfg := config.Config{}
+	cfg.Storage.Backend = "memory"
+
+	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	rr := httptest.NewRecorder()
+	healthHandler(nil, cfg)(rr, req)
+
+	var health healthStatus
+	if err := json.NewDecoder(rr.Body).Decode(&health); err != nil {
+		t.Fatalf("failed to decode health: %v", err)
+	}
+	if rr.Code != http.StatusOK || health.Status != "ok" || health.Degraded || health.Storage != "memory" {
+		t.Fatalf("unexpected health response %d: %+v", rr.Code, health)
+	}
+	if health.TagRejections.BySource != nil {
+		t.Fatalf("expected per-sender tag rejections to be hidden without auth, got %+v", health.TagRejections)
+	}
+}
+
 func sessionExpiryLater() (later time.Time) {
 	return time.Now().Add(sessionExpiry)
 }
+
+func TestAlarmsHandlerAcknowledgesAlarm(t *testing.T) {
+	sessionTokens = map[string]sessionData{
+		"token": {Username: "admin", Role: roleAdmin, Expires: sessionExpiryLater()},
+	}
+	alarms := store.NewMemoryStore()
+	alarms.Put(context.Background(), store.Record{ID: "abc", Tag: "ALARM", ReceivedAt: time.Now(), Value: `{"tag":"ALARM","message":"disk full"}`})
+	events := store.NewMemoryEventLog(0)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/alarms/abc/ack", strings.NewReader(`{"comment":"looking into it"}`))
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "token"})
+	rr := httptest.NewRecorder()
+	alarmsHandler(alarms, events, nil, nil, nil, config.Config{})(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rec, _ := alarms.Get(context.Background(), "abc")
+	doc, _ := alarm.Decode(rec)
+	if doc.State.Status != alarm.StatusAcknowledged || doc.State.Ack == nil || doc.State.Ack.By != "admin" || doc.State.Ack.Comment != "looking into it" {
+		t.Fatalf("expected alarm acknowledged by admin with comment, got %+v", doc.State)
+	}
+
+	history, _ := events.Events(context.Background(), store.EventQuery{})
+	if len(history.Events) != 1 || history.Events[0].Type != store.EventAck || history.Events[0].Actor != "admin" {
+		t.Fatalf("expected one ack event by admin, got %+v", history.Events)
+	}
+
+	// A second ack conflicts; unack returns the alarm to open.
+	rr = httptest.NewRecorder()
+	alarmsHandler(alarms, events, nil, nil, nil, config.Config{})(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for repeated ack, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/alarms/abc/unack", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "token"})
+	rr = httptest.NewRecorder()
+	alarmsHandler(alarms, events, nil, nil, nil, config.Config{})(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for unack, got %d", rr.Code)
+	}
+	rec, _ = alarms.Get(context.Background(), "abc")
+	if doc, _ := alarm.Decode(rec); doc.State.Status != alarm.StatusOpen || doc.Acknowledged() {
+		t.Fatalf("expected alarm to be open again, got %+v", doc.State)
+	}
+}
+
+func TestAlarmsHandlerReadOnlyAckRequiresConfig(t *testing.T) {
+	sessionTokens = map[string]sessionData{
+		"token": {Username: "viewer", Role: roleReadOnly, Expires: sessionExpiryLater()},
+	}
+	alarms := store.NewMemoryStore()
+	alarms.Put(context.Background(), store.Record{ID: "abc", Tag: "ALARM", ReceivedAt: time.Now(), Value: `{"tag":"ALARM"}`})
+
+	ack := func(cfg config.Config) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/alarms/abc/ack", nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "token"})
+		rr := httptest.NewRecorder()
+		alarmsHandler(alarms, store.NewMemoryEventLog(0), nil, nil, nil, cfg)(rr, req)
+		return rr.Code
+	}
+
+	if code := ack(config.Config{}); code != http.StatusForbidden {
+		t.Fatalf("expected readonly ack to be forbidden by default, got %d", code)
+	}
+	cfg := config.Config{}
+	cfg.Web.ReadOnlyCanAck = true
+	if code := ack(cfg); code != http.StatusOK {
+		t.Fatalf("expected readonly ack to be allowed with readonly_can_ack, got %d", code)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/alarms/abc", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "token"})
+	rr := httptest.NewRecorder()
+	alarmsHandler(alarms, store.NewMemoryEventLog(0), nil, nil, nil, cfg)(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected readonly delete to stay forbidden, got %d", rr.Code)
+	}
+}
+
+func TestAlarmsHandlerResolveRequiresAdminAndRecordsHistory(t *testing.T) {
+	cfg := config.Config{}
+	cfg.Web.ReadOnlyCanAck = true
+	sessionTokens = map[string]sessionData{
+		"viewer": {Username: "viewer", Role: roleReadOnly, Expires: sessionExpiryLater()},
+		"admin":  {Username: "admin", Role: roleAdmin, Expires: sessionExpiryLater()},
+	}
+	alarms := store.NewMemoryStore()
+	alarms.Put(context.Background(), store.Record{ID: "abc", Tag: "ALARM", ReceivedAt: time.Now(), Value: `{"tag":"ALARM"}`})
+	events := store.NewMemoryEventLog(0)
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		rr := httptest.NewRecorder()
+		alarmsHandler(alarms, events, nil, nil, nil, cfg)(rr, req)
+		return rr
+	}
+
+	if rr := do(http.MethodPost, "/api/alarms/abc/resolve", "viewer"); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected readonly resolve to be forbidden, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/alarms/abc/resolve", "admin"); rr.Code != http.StatusOK {
+		t.Fatalf("expected admin resolve to succeed, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/alarms/abc/ack", "admin"); rr.Code != http.StatusConflict {
+		t.Fatalf("expected ack of a resolved alarm to conflict, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/alarms/abc/archive", "admin"); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected unknown action to be 404, got %d", rr.Code)
+	}
+
+	rr := do(http.MethodGet, "/api/alarms/abc/history", "admin")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for history, got %d", rr.Code)
+	}
+	var history []alarm.Transition
+	if err := json.NewDecoder(rr.Body).Decode(&history); err != nil {
+		t.Fatalf("failed to decode history: %v", err)
+	}
+	if len(history) != 1 || history[0].To != alarm.StatusResolved || history[0].By != "admin" {
+		t.Fatalf("expected one resolve transition by admin, got %+v", history)
+	}
+}
+
+func TestDeleteMovesAlarmToRecycleBin(t *testing.T) {
+	cfg := config.Config{}
+	cfg.API.BearerToken = "api-token"
+	alarms := store.NewMemoryStore()
+	alarms.Put(context.Background(), store.Record{ID: "abc", Tag: "INSIGHTS", ReceivedAt: time.Now(), Value: `{"tag":"INSIGHTS"}`})
+	events := store.NewMemoryEventLog(0)
+	bin := trash.New(alarms, store.NewMemoryStore(), events, 0)
+
+	do := func(handler http.HandlerFunc, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer api-token")
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	if rr := do(alarmsHandler(alarms, events, bin, nil, nil, cfg), http.MethodDelete, "/api/alarms", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for delete all, got %d", rr.Code)
+	}
+
+	rr := do(trashHandler(bin, cfg), http.MethodGet, "/api/trash", "")
+	var page struct {
+		Entries []trash.Entry `json:"entries"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil || len(page.Entries) != 1 || page.Entries[0].DeletedBy != "api" {
+		t.Fatalf("expected the deleted alarm in the bin, got %+v, %v", page.Entries, err)
+	}
+
+	rr = do(trashHandler(bin, cfg), http.MethodPost, "/api/trash/restore", `{"all":true}`)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"abc":"restored"`) {
+		t.Fatalf("expected abc to be restored, got %d %s", rr.Code, rr.Body.String())
+	}
+	if _, err := alarms.Get(context.Background(), "abc"); err != nil {
+		t.Fatalf("expected abc back in the alarm store: %v", err)
+	}
+}
+
+func TestTrashHandlerRequiresAdmin(t *testing.T) {
+	sessionTokens = map[string]sessionData{
+		"token": {Username: "viewer", Role: roleReadOnly, Expires: sessionExpiryLater()},
+	}
+	bin := trash.New(store.NewMemoryStore(), store.NewMemoryStore(), store.NewMemoryEventLog(0), 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/trash", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "token"})
+	rr := httptest.NewRecorder()
+	trashHandler(bin, config.Config{})(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected readonly access to the bin to be forbidden, got %d", rr.Code)
+	}
+}
+
+func TestAlarmsHandlerCommentsThread(t *testing.T) {
+	sessionTokens = map[string]sessionData{
+		"viewer": {Username: "viewer", Role: roleReadOnly, Expires: sessionExpiryLater()},
+		"admin":  {Username: "admin", Role: roleAdmin, Expires: sessionExpiryLater()},
+	}
+	alarms := store.NewMemoryStore()
+	alarms.Put(context.Background(), store.Record{ID: "abc", Tag: "INSIGHTS", ReceivedAt: time.Now(), Value: `{"tag":"INSIGHTS"}`})
+	events := store.NewMemoryEventLog(0)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		rr := httptest.NewRecorder()
+		alarmsHandler(alarms, events, nil, nil, nil, config.Config{})(rr, req)
+		return rr
+	}
+
+	if rr := do(http.MethodPost, "/api/alarms/abc/comments", "viewer", `{"text":"note"}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected readonly comment to be forbidden by default, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/alarms/abc/comments", "admin", `{"text":"  "}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected an empty comment to be rejected, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/alarms/missing/comments", "admin", `{"text":"note"}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing alarm, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/alarms/abc/comments", "admin", `{"text":"Host reimaged, <b>closing</b>"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for a new comment, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr := do(http.MethodGet, "/api/alarms/abc/comments", "viewer", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for comments, got %d", rr.Code)
+	}
+	var comments []alarm.Comment
+	if err := json.NewDecoder(rr.Body).Decode(&comments); err != nil {
+		t.Fatalf("failed to decode comments: %v", err)
+	}
+	if len(comments) != 1 || comments[0].By != "admin" || comments[0].Text != "Host reimaged, <b>closing</b>" {
+		t.Fatalf("expected the admin comment stored as plain text, got %+v", comments)
+	}
+}
+
+func TestAlarmsHandlerAssignsAndFiltersByAssignee(t *testing.T) {
+	cfg := config.Config{}
+	cfg.Web.ReadOnlyCanAck = true
+	cfg.Web.Users = []struct {
+		Username   string `mapstructure:"username"`
+		Secret     string `mapstructure:"secret"`
+		SecretHash string `mapstructure:"secret_hash"`
+		Role       string `mapstructure:"role"`
+	}{
+		{Username: "admin", Role: roleAdmin},
+		{Username: "alice", Role: roleReadOnly},
+		{Username: "bob", Role: roleReadOnly},
+	}
+	sessionTokens = map[string]sessionData{
+		"admin": {Username: "admin", Role: roleAdmin, Expires: sessionExpiryLater()},
+		"alice": {Username: "alice", Role: roleReadOnly, Expires: sessionExpiryLater()},
+		"bob":   {Username: "bob", Role: roleReadOnly, Expires: sessionExpiryLater()},
+	}
+	alarms := store.NewMemoryStore()
+	now := time.Now()
+	alarms.Put(context.Background(), store.Record{ID: "a1", Tag: "ALARM", ReceivedAt: now, Value: `{"tag":"ALARM"}`})
+	alarms.Put(context.Background(), store.Record{ID: "a2", Tag: "ALARM", ReceivedAt: now.Add(-time.Minute), Value: `{"tag":"ALARM"}`})
+	events := store.NewMemoryEventLog(0)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		rr := httptest.NewRecorder()
+		alarmsHandler(alarms, events, nil, nil, nil, cfg)(rr, req)
+		return rr
+	}
+
+	if rr := do(http.MethodPost, "/api/alarms/a1/assign", "alice", `{"assignee":"bob"}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected assigning someone else to be admin only, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/alarms/a1/assign", "alice", `{"assignee":"alice"}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected a user to claim an alarm, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPost, "/api/alarms/a1/assign", "bob", `{"assignee":""}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected releasing someone else's claim to be admin only, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/alarms/a1/assign", "bob", `{"assignee":"bob"}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected taking over someone else's claim to be admin only, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/alarms/bulk", "bob", `{"action":"assign","assignee":"bob","keys":["a1"]}`); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"a1":"conflict"`) {
+		t.Fatalf("expected bulk claim of someone else's alarm to conflict, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPost, "/api/alarms/a2/assign", "admin", `{"assignee":"mallory"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected an unknown assignee to be rejected, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/alarms/a2/assign", "admin", `{"assignee":"bob"}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected admin to assign bob, got %d", rr.Code)
+	}
+
+	rr := do(http.MethodGet, "/api/alarms?assignee=me", "alice", "")
+	var page struct {
+		Alarms []struct {
+			Key   string         `json:"key"`
+			Alarm alarm.Document `json:"alarm"`
+		} `json:"alarms"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatalf("failed to decode page: %v", err)
+	}
+	if len(page.Alarms) != 1 || page.Alarms[0].Key != "a1" || page.Alarms[0].Alarm.Assignee != "alice" {
+		t.Fatalf("expected only alice's alarm, got %+v", page.Alarms)
+	}
+
+	history, _ := events.Events(context.Background(), store.EventQuery{})
+	if len(history.Events) != 2 || history.Events[0].Type != store.EventAssign || history.Events[0].Actor != "admin" {
+		t.Fatalf("expected two assign events, got %+v", history.Events)
+	}
+}
+
+func TestSilencesHandlerCreatesForAdminsOnly(t *testing.T) {
+	sessionTokens = map[string]sessionData{
+		"viewer": {Username: "viewer", Role: roleReadOnly, Expires: sessionExpiryLater()},
+		"admin":  {Username: "admin", Role: roleAdmin, Expires: sessionExpiryLater()},
+	}
+	silences := silence.New(store.NewMemoryStore(), 0, nil)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		rr := httptest.NewRecorder()
+		silencesHandler(silences, config.Config{})(rr, req)
+		return rr
+	}
+
+	body := `{"matchers":{"tag":"INSIGHTS","rule_name":"Port Scan"},"duration":"2h","comment":"Scanner maintenance"}`
+	if rr := do(http.MethodPost, "/api/silences", "viewer", body); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected readonly users not to create silences, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/silences", "admin", `{"duration":"2h"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected a silence without matchers to be rejected, got %d", rr.Code)
+	}
+	rr := do(http.MethodPost, "/api/silences", "admin", body)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do(http.MethodGet, "/api/silences", "viewer", "")
+	var list []silenceView
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode silences: %v", err)
+	}
+	if len(list) != 1 || list[0].Status != silence.StatusActive || list[0].CreatedBy != "admin" || list[0].EndsAt.Sub(list[0].StartsAt) != 2*time.Hour {
+		t.Fatalf("expected one active two-hour silence by admin, got %+v", list)
+	}
+
+	if rr := do(http.MethodDelete, "/api/silences/"+list[0].ID, "admin", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for delete, got %d", rr.Code)
+	}
+	if rr := do(http.MethodDelete, "/api/silences/"+list[0].ID, "admin", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a deleted silence, got %d", rr.Code)
+	}
+}
+
+func TestMaintenanceHandlerListsUpcomingWindows(t *testing.T) {
+	calendar, err := maintenance.New([]maintenance.WindowConfig{{Name: "daily", Schedule: "0 3 * * *", Duration: time.Hour, Tags: []string{"ALARM"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	maintenanceHandler(calendar)(rr, httptest.NewRequest(http.MethodGet, "/api/maintenance?days=7", nil))
+	var upcoming []maintenance.Occurrence
+	if err := json.NewDecoder(rr.Body).Decode(&upcoming); err != nil {
+		t.Fatalf("failed to decode windows: %v", err)
+	}
+	if len(upcoming) < 7 || len(upcoming) > 8 || upcoming[0].Window != "daily" {
+		t.Fatalf("expected a week of daily windows, got %d: %+v", len(upcoming), upcoming)
+	}
+
+	rr = httptest.NewRecorder()
+	maintenanceHandler(nil)(rr, httptest.NewRequest(http.MethodGet, "/api/maintenance", nil))
+	if body := strings.TrimSpace(rr.Body.String()); body != "[]" {
+		t.Fatalf("expected an empty list without windows, got %s", body)
+	}
+}
+
+func TestIncidentsHandlerCascadesActions(t *testing.T) {
+	ctx := context.Background()
+	sessionTokens = map[string]sessionData{
+		"viewer": {Username: "viewer", Role: roleReadOnly, Expires: sessionExpiryLater()},
+		"admin":  {Username: "admin", Role: roleAdmin, Expires: sessionExpiryLater()},
+	}
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	incidents, err := incident.New(alarms, store.NewMemoryStore(), events, []incident.Rule{
+		{Name: "per-sender", Fields: []string{"sender"}, Window: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var id string
+	for _, key := range []string{"a1", "a2"} {
+		doc := alarm.New(key, "ALARM", time.Now())
+		doc.Source.IP = "10.0.0.5"
+		rec, _ := doc.Record()
+		alarms.Put(ctx, rec)
+		inc, _, _ := incidents.Group(ctx, &doc, true)
+		id = inc.ID
+	}
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		rr := httptest.NewRecorder()
+		incidentsHandler(incidents, config.Config{})(rr, req)
+		return rr
+	}
+
+	var page incidentPage
+	if err := json.NewDecoder(do(http.MethodGet, "/api/incidents", "viewer").Body).Decode(&page); err != nil {
+		t.Fatalf("failed to decode incidents: %v", err)
+	}
+	if len(page.Incidents) != 1 || page.Incidents[0].Count != 2 {
+		t.Fatalf("expected one incident with two alarms, got %+v", page.Incidents)
+	}
+
+	if rr := do(http.MethodPost, "/api/incidents/"+id+"/resolve", "viewer"); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected readonly resolve to be forbidden, got %d", rr.Code)
+	}
+	rr := do(http.MethodPost, "/api/incidents/"+id+"/resolve", "admin")
+	var result incidentResult
+	if err := json.NewDecoder(rr.Body).Decode(&result); err != nil || result.Changed != 2 {
+		t.Fatalf("expected both alarms to be resolved, got %d %+v", rr.Code, result)
+	}
+
+	var detail incidentDetail
+	if err := json.NewDecoder(do(http.MethodGet, "/api/incidents/"+id, "viewer").Body).Decode(&detail); err != nil {
+		t.Fatalf("failed to decode incident: %v", err)
+	}
+	if detail.Status() != alarm.StatusResolved || detail.Counts[alarm.StatusResolved] != 2 || len(detail.Alarms) != 2 {
+		t.Fatalf("expected a resolved incident with two resolved alarms, got %+v", detail)
+	}
+	if rr := do(http.MethodGet, "/api/incidents/missing", "viewer"); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected unknown incident to be 404, got %d", rr.Code)
+	}
+}
+
+func TestAlarmsHandlerBulkActions(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Config{}
+	cfg.Web.ReadOnlyCanAck = true
+	sessionTokens = map[string]sessionData{
+		"viewer": {Username: "viewer", Role: roleReadOnly, Expires: sessionExpiryLater()},
+		"admin":  {Username: "admin", Role: roleAdmin, Expires: sessionExpiryLater()},
+	}
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+	for i, id := range []string{"a1", "a2", "a3"} {
+		doc := alarm.New(id, "ALARM", time.Now().Add(-time.Duration(i)*time.Minute))
+		doc.Source.IP = "10.0.0.5"
+		if id == "a3" {
+			doc.Source.IP = "10.9.0.5"
+		}
+		rec, _ := doc.Record()
+		alarms.Put(ctx, rec)
+	}
+
+	do := func(token, body string) (int, bulkResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/api/alarms/bulk", strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		rr := httptest.NewRecorder()
+		alarmsHandler(alarms, events, nil, nil, nil, cfg)(rr, req)
+		var resp bulkResponse
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return rr.Code, resp
+	}
+
+	if code, resp := do("viewer", `{"action":"ack","filter":"sender=10.0.0.0/24","comment":"on it"}`); code != http.StatusOK || resp.Matched != 2 || resp.Results["a1"] != bulkOK || resp.Results["a2"] != bulkOK {
+		t.Fatalf("expected both alarms from the subnet to be acknowledged, got %d %+v", code, resp)
+	}
+	if code, resp := do("admin", `{"action":"resolve","keys":["a1","a3","missing"]}`); code != http.StatusOK || resp.Results["a1"] != bulkOK || resp.Results["a3"] != bulkOK || resp.Results["missing"] != bulkNotFound {
+		t.Fatalf("expected per-key resolve results, got %d %+v", code, resp)
+	}
+	if _, resp := do("admin", `{"action":"ack","keys":["a1"]}`); resp.Results["a1"] != bulkConflict {
+		t.Fatalf("expected ack of a resolved alarm to conflict, got %+v", resp)
+	}
+	if _, resp := do("viewer", `{"action":"tag","filter":"status=acknowledged","add":["noisy"]}`); resp.Matched != 1 || resp.Results["a2"] != bulkOK {
+		t.Fatalf("expected the acknowledged alarm to be labelled, got %+v", resp)
+	}
+	if _, resp := do("viewer", `{"action":"tag","keys":["a2"],"add":["NOISY"]}`); resp.Results["a2"] != bulkUnchanged {
+		t.Fatalf("expected an existing label to leave the alarm unchanged, got %+v", resp)
+	}
+
+	if code, _ := do("viewer", `{"action":"delete","filter":"label=noisy"}`); code != http.StatusForbidden {
+		t.Fatalf("expected readonly bulk delete to be forbidden, got %d", code)
+	}
+	if code, _ := do("admin", `{"action":"delete"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected a bulk request without keys or filter to be rejected, got %d", code)
+	}
+	if code, resp := do("admin", `{"action":"delete","filter":"label=noisy"}`); code != http.StatusOK || resp.Results["a2"] != bulkOK {
+		t.Fatalf("expected the labelled alarm to be deleted, got %d %+v", code, resp)
+	}
+	if _, err := alarms.Get(ctx, "a2"); err != store.ErrNotFound {
+		t.Fatalf("expected a2 to be deleted, got %v", err)
+	}
+}
+
+func TestAlarmsHandlerCreatesManualAlarm(t *testing.T) {
+	cfg := config.Config{}
+	sessionTokens = map[string]sessionData{
+		"viewer": {Username: "viewer", Role: roleReadOnly, Expires: sessionExpiryLater()},
+		"admin":  {Username: "admin", Role: roleAdmin, Expires: sessionExpiryLater()},
+	}
+	alarms := store.NewMemoryStore()
+	events := store.NewMemoryEventLog(0)
+
+	do := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/alarms", strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		rr := httptest.NewRecorder()
+		alarmsHandler(alarms, events, nil, nil, nil, cfg)(rr, req)
+		return rr
+	}
+
+	if rr := do("viewer", `{"tag":"PHONE","message":"caller reports outage"}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected readonly users to be forbidden, got %d", rr.Code)
+	}
+	for _, body := range []string{
+		`{"message":"no tag"}`,
+		`{"tag":"bad tag","message":"x"}`,
+		`{"tag":"PHONE"}`,
+		`{"tag":"PHONE","message":"x","severity":"urgent"}`,
+		`{"tag":"PHONE","message":"x","source":{"ip":"not-an-ip"}}`,
+	} {
+		if rr := do("admin", body); rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected %s to be rejected, got %d", body, rr.Code)
+		}
+	}
+
+	rr := do("admin", `{"tag":"PHONE","severity":"CRIT","message":" caller reports outage ","fields":{"site":"hq"},"source":{"hostname":"edge1"}}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var doc alarm.Document
+	json.NewDecoder(rr.Body).Decode(&doc)
+	if doc.CreatedBy != "admin" || doc.Severity != "crit" || doc.Fields["message"] != "caller reports outage" || doc.Fields["site"] != "hq" || doc.Status() != alarm.StatusOpen {
+		t.Fatalf("unexpected manual alarm: %+v", doc)
+	}
+	if _, err := alarms.Get(context.Background(), doc.ID); err != nil {
+		t.Fatalf("expected the alarm to be stored: %v", err)
+	}
+	page, err := events.Events(context.Background(), store.EventQuery{AlarmID: doc.ID})
+	if err != nil || len(page.Events) != 1 || page.Events[0].Type != store.EventIngest || page.Events[0].Actor != "admin" {
+		t.Fatalf("expected an ingest event by admin, got %+v, %v", page.Events, err)
+	}
+
+	cfg.Web.ReadOnlyCanAck = true
+	if rr := do("viewer", `{"tag":"PHONE","message":"second report"}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected readonly users to be forbidden even with ack rights, got %d", rr.Code)
+	}
+}
+
+func TestSuppressedAlarmsAreNotNotified(t *testing.T) {
+	keys := make(chan string, 10)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		keys <- payload["key"] + " " + payload["status"]
+	}))
+	defer api.Close()
+
+	cfg := config.Config{}
+	cfg.ExternalAPI.Enabled = true
+	cfg.ExternalAPI.URL = api.URL
+	cfg.ExternalAPI.Method = http.MethodPost
+
+	silenced := alarm.New("silenced", "ALARM", time.Now())
+	silenced.SilencedBy = "s1"
+	maintained := alarm.New("maintained", "ALARM", time.Now())
+	maintained.Maintenance = "patching"
+	announced := alarm.New("announced", "ALARM", time.Now())
+
+	for _, doc := range []alarm.Document{silenced, maintained} {
+		notifyStatus(doc, cfg)
+		notifyAssigned(doc, cfg)
+		if rec, _ := doc.Record(); clearsOnDelete(rec) {
+			t.Fatalf("expected deleting %s not to send a CLEAR", doc.ID)
+		}
+	}
+	if rec, _ := announced.Record(); !clearsOnDelete(rec) {
+		t.Fatal("expected deleting an announced alarm to send a CLEAR")
+	}
+	notifyStatus(announced, cfg)
+
+	select {
+	case got := <-keys:
+		if got != store.Key("announced")+" OPEN" {
+			t.Fatalf("expected only the announced alarm to be notified, got %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the announced alarm to be notified")
+	}
+}
@@ -0,0 +1,158 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"logvault/alarm"
+	"logvault/config"
+	"logvault/incident"
+	"logvault/store"
+)
+
+type incidentPage struct {
+	Incidents  []incident.Incident `json:"incidents"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// incidentDetail is an incident with its alarms and how many of them are
+// in each status.
+type incidentDetail struct {
+	incident.Incident
+	Alarms []alarm.Document `json:"alarms"`
+	Counts map[string]int   `json:"counts"`
+}
+
+// incidentResult is the response to an incident action: the incident and
+// how many of its alarms the action was applied to.
+type incidentResult struct {
+	Incident incident.Incident `json:"incident"`
+	Changed  int               `json:"changed"`
+}
+
+// incidentsHandler serves GET /api/incidents, GET /api/incidents/{id} and
+// POST /api/incidents/{id}/{action}, where action is a lifecycle action
+// that is applied to the incident and then to each of its alarms.
+func incidentsHandler(incidents *incident.Grouper, appConfig config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/incidents"), "/")
+		id, action, _ := strings.Cut(rest, "/")
+		switch {
+		case r.Method == http.MethodGet && id == "":
+			listIncidents(w, r, incidents)
+		case r.Method == http.MethodGet && action == "":
+			getIncident(w, incidents, id)
+		case r.Method == http.MethodPost && id != "" && action != "":
+			incidentAction(w, r, incidents, id, action, appConfig)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// listIncidents serves pages of incidents, most recently active first.
+// Supported query parameters are limit, cursor and tag.
+func listIncidents(w http.ResponseWriter, r *http.Request, incidents *incident.Grouper) {
+	params := r.URL.Query()
+	q := store.Query{Tag: params.Get("tag"), Cursor: params.Get("cursor"), Limit: defaultAlarmPageSize}
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if limit > maxAlarmPageSize {
+			limit = maxAlarmPageSize
+		}
+		q.Limit = limit
+	}
+
+	list, next, err := incidents.Query(context.Background(), q)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to query incidents: %v", err)
+		http.Error(w, "Failed to list incidents", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incidentPage{Incidents: list, NextCursor: next})
+}
+
+func getIncident(w http.ResponseWriter, incidents *incident.Grouper, id string) {
+	ctx := context.Background()
+	inc, err := incidents.Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Incident not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get incident %s: %v", id, err)
+		http.Error(w, "Failed to get incident", http.StatusInternalServerError)
+		return
+	}
+	docs, err := incidents.Members(ctx, inc)
+	if err != nil {
+		log.Printf("Failed to get alarms of incident %s: %v", id, err)
+		http.Error(w, "Failed to get incident", http.StatusInternalServerError)
+		return
+	}
+
+	detail := incidentDetail{Incident: inc, Alarms: docs, Counts: make(map[string]int)}
+	for _, doc := range docs {
+		detail.Counts[doc.Status()]++
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+func incidentAction(w http.ResponseWriter, r *http.Request, incidents *incident.Grouper, id, name string, appConfig config.Config) {
+	action, ok := alarm.ActionByName(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if !canApplyAction(r, action, appConfig) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	req, err := decodeActionRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	actor := requestActor(r)
+	inc, changed, err := incidents.Change(context.Background(), id, action, actor, req.Comment)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Incident not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, alarm.ErrInvalidTransition) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to %s incident %s via API: %v", action.Name, id, err)
+		http.Error(w, "Failed to update incident", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("API: %s incident %s by %s, now %s, %d alarms changed", action.Name, id, actor, inc.Status(), changed)
+
+	// One notification for the incident rather than one per alarm
+	if appConfig.ExternalAPI.Enabled {
+		incident.Notify(appConfig, inc, strings.ToUpper(inc.Status()))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incidentResult{Incident: inc, Changed: changed})
+}
@@ -7,8 +7,12 @@ import (
 	"strings"
 
 	"logvault/config"
+	"logvault/incident"
 	"logvault/internal/allowlist"
-	"logvault/redis"
+	"logvault/silence"
+	"logvault/spool"
+	"logvault/store"
+	"logvault/trash"
 )
 
 // MimeTypeMiddleware sets the correct Content-Type for static assets.
@@ -27,7 +31,7 @@ func MimeTypeMiddleware(next http.Handler) http.Handler {
 }
 
 // StartServer initializes and starts the web server
-func StartServer(rdb *redis.RedisClient, appConfig config.Config) {
+func StartServer(alarms store.AlarmStore, events store.EventLog, bin *trash.Bin, silences *silence.Silencer, incidents *incident.Grouper, spooler *spool.Spool, appConfig config.Config) {
 	mux := http.NewServeMux()
 
 	// Serve static files from the client directory
@@ -43,15 +47,25 @@ func StartServer(rdb *redis.RedisClient, appConfig config.Config) {
 		LoginHandler(w, r, appConfig)
 	})
 
+	mux.HandleFunc("/api/health", healthHandler(spooler, appConfig))
+
 	// API routes
-	mux.HandleFunc("/api/data", APIAuthMiddleware(getAllRedisDataHandler(rdb), appConfig))
-	mux.HandleFunc("/api/session", AuthMiddleware(sessionInfoHandler))
+	mux.HandleFunc("/api/data", APIAuthMiddleware(getAllRedisDataHandler(alarms), appConfig))
+	mux.HandleFunc("/api/session", AuthMiddleware(sessionInfoHandler(appConfig)))
+	mux.HandleFunc("/api/events", APIAuthMiddleware(eventsHandler(events), appConfig))
+	mux.HandleFunc("/api/trash", APIAuthMiddleware(trashHandler(bin, appConfig), appConfig))
+	mux.HandleFunc("/api/trash/", APIAuthMiddleware(trashHandler(bin, appConfig), appConfig))
+	mux.HandleFunc("/api/silences", APIAuthMiddleware(silencesHandler(silences, appConfig), appConfig))
+	mux.HandleFunc("/api/silences/", APIAuthMiddleware(silencesHandler(silences, appConfig), appConfig))
+	mux.HandleFunc("/api/incidents", APIAuthMiddleware(incidentsHandler(incidents, appConfig), appConfig))
+	mux.HandleFunc("/api/incidents/", APIAuthMiddleware(incidentsHandler(incidents, appConfig), appConfig))
+	mux.HandleFunc("/api/maintenance", APIAuthMiddleware(maintenanceHandler(silences.Calendar()), appConfig))
 
 	// Protected web UI routes
 	mux.HandleFunc("/logout", LogoutHandler) // Add logout handler
-	mux.HandleFunc("/", AuthMiddleware(serveHome(rdb)))
-	mux.HandleFunc("/api/alarms", APIAuthMiddleware(alarmsHandler(rdb, appConfig), appConfig))
-	mux.HandleFunc("/api/alarms/", APIAuthMiddleware(alarmsHandler(rdb, appConfig), appConfig)) // For DELETE requests with key
+	mux.HandleFunc("/", AuthMiddleware(serveHome()))
+	mux.HandleFunc("/api/alarms", APIAuthMiddleware(alarmsHandler(alarms, events, bin, silences, incidents, appConfig), appConfig))
+	mux.HandleFunc("/api/alarms/", APIAuthMiddleware(alarmsHandler(alarms, events, bin, silences, incidents, appConfig), appConfig)) // DELETE /api/alarms/{key} and POST /api/alarms/{key}/{action}
 
 	addr := fmt.Sprintf(":%d", appConfig.Web.Port)
 	handler := ipAllowlistMiddleware(corsMiddleware(mux, []string{appConfig.Web.CORSOrigin}, true, true), appConfig)
@@ -0,0 +1,165 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"logvault/config"
+	"logvault/maintenance"
+	"logvault/silence"
+)
+
+// silenceView is a silence as served by the API, with its current status.
+type silenceView struct {
+	silence.Silence
+	Status string `json:"status"`
+}
+
+// silenceRequest is the body of POST /api/silences. The end is either
+// ends_at or duration after the start, e.g. "2h".
+type silenceRequest struct {
+	Matchers silence.Matchers `json:"matchers"`
+	StartsAt time.Time        `json:"starts_at"`
+	EndsAt   time.Time        `json:"ends_at"`
+	Duration string           `json:"duration"`
+	Comment  string           `json:"comment"`
+}
+
+// silencesHandler serves GET /api/silences to anyone who may view alarms,
+// and POST /api/silences and DELETE /api/silences/{id} to admins and bearer
+// token clients.
+func silencesHandler(silences *silence.Silencer, appConfig config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/silences"), "/")
+		switch {
+		case r.Method == http.MethodGet && id == "":
+			listSilences(w, silences)
+		case r.Method == http.MethodPost && id == "":
+			if !canDeleteAlarms(r, appConfig) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			createSilence(w, r, silences)
+		case r.Method == http.MethodDelete && id != "":
+			if !canDeleteAlarms(r, appConfig) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			deleteSilence(w, r, silences, id)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func listSilences(w http.ResponseWriter, silences *silence.Silencer) {
+	now := time.Now()
+	list := silences.List()
+	views := make([]silenceView, len(list))
+	for i, s := range list {
+		views[i] = silenceView{Silence: s, Status: s.Status(now)}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(views)
+}
+
+func createSilence(w http.ResponseWriter, r *http.Request, silences *silence.Silencer) {
+	var req silenceRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxActionBodyBytes)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	s := silence.Silence{
+		Matchers:  req.Matchers,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		CreatedBy: requestActor(r),
+		Comment:   req.Comment,
+	}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid duration", http.StatusBadRequest)
+			return
+		}
+		if s.StartsAt.IsZero() {
+			s.StartsAt = time.Now()
+		}
+		s.EndsAt = s.StartsAt.Add(d)
+	}
+
+	s, err := silences.Create(context.Background(), s)
+	if errors.Is(err, silence.ErrInvalid) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create silence: %v", err)
+		http.Error(w, "Failed to create silence", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("API: %s created silence %s until %s", s.CreatedBy, s.ID, s.EndsAt.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(silenceView{Silence: s, Status: s.Status(time.Now())})
+}
+
+func deleteSilence(w http.ResponseWriter, r *http.Request, silences *silence.Silencer, id string) {
+	found, err := silences.Delete(context.Background(), id)
+	if err != nil {
+		log.Printf("Failed to delete silence %s: %v", id, err)
+		http.Error(w, "Failed to delete silence", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Silence not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("API: %s deleted silence %s", requestActor(r), id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maxMaintenanceDays bounds how far ahead /api/maintenance lists windows.
+const maxMaintenanceDays = 90
+
+// maintenanceHandler serves GET /api/maintenance, the maintenance windows in
+// progress or starting within the next days (default 14).
+func maintenanceHandler(calendar *maintenance.Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		days := 14
+		if v := r.URL.Query().Get("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				http.Error(w, "Invalid days", http.StatusBadRequest)
+				return
+			}
+			if n > maxMaintenanceDays {
+				n = maxMaintenanceDays
+			}
+			days = n
+		}
+
+		now := time.Now()
+		upcoming := calendar.Upcoming(now, now.AddDate(0, 0, days))
+		if upcoming == nil {
+			upcoming = []maintenance.Occurrence{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(upcoming)
+	}
+}
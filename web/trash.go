@@ -0,0 +1,175 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"logvault/config"
+	"logvault/store"
+	"logvault/trash"
+)
+
+type trashPage struct {
+	Entries    []trash.Entry `json:"entries"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// trashRequest selects recycle bin entries by key, or all of them.
+type trashRequest struct {
+	Keys []string `json:"keys"`
+	All  bool     `json:"all"`
+}
+
+// trashHandler serves the recycle bin to admins and bearer token clients:
+// GET /api/trash lists deleted alarms, POST /api/trash/restore and
+// POST /api/trash/purge take {"keys": [...]} or {"all": true}.
+func trashHandler(bin *trash.Bin, appConfig config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canDeleteAlarms(r, appConfig) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if bin == nil {
+			http.Error(w, "Recycle bin is disabled", http.StatusNotFound)
+			return
+		}
+
+		action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/trash"), "/")
+		switch {
+		case r.Method == http.MethodGet && action == "":
+			listTrash(w, r, bin)
+		case r.Method == http.MethodPost && (action == "restore" || action == "purge"):
+			req, ok := decodeTrashRequest(w, r)
+			if !ok {
+				return
+			}
+			if action == "restore" {
+				restoreTrash(w, r, bin, req)
+			} else {
+				purgeTrash(w, r, bin, req)
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func decodeTrashRequest(w http.ResponseWriter, r *http.Request) (trashRequest, bool) {
+	var req trashRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxActionBodyBytes)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	if !req.All && len(req.Keys) == 0 {
+		http.Error(w, "Either keys or all is required", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+func allTrashKeys(ctx context.Context, bin *trash.Bin) ([]string, error) {
+	var keys []string
+	q := store.Query{Limit: maxAlarmPageSize}
+	for {
+		page, err := bin.List(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range page.Entries {
+			keys = append(keys, entry.ID)
+		}
+		if page.NextCursor == "" {
+			return keys, nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+// listTrash serves newest-deleted-first pages of the recycle bin. Supported
+// query parameters are limit, cursor and tag.
+func listTrash(w http.ResponseWriter, r *http.Request, bin *trash.Bin) {
+	params := r.URL.Query()
+	q := store.Query{
+		Tag:    params.Get("tag"),
+		Cursor: params.Get("cursor"),
+		Limit:  defaultAlarmPageSize,
+	}
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if limit > maxAlarmPageSize {
+			limit = maxAlarmPageSize
+		}
+		q.Limit = limit
+	}
+
+	page, err := bin.List(context.Background(), q)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to list recycle bin: %v", err)
+		http.Error(w, "Failed to list recycle bin", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trashPage{Entries: page.Entries, NextCursor: page.NextCursor})
+}
+
+func restoreTrash(w http.ResponseWriter, r *http.Request, bin *trash.Bin, req trashRequest) {
+	ctx := context.Background()
+	keys := req.Keys
+	if req.All {
+		var err error
+		if keys, err = allTrashKeys(ctx, bin); err != nil {
+			log.Printf("Failed to list recycle bin: %v", err)
+			http.Error(w, "Failed to list recycle bin", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	actor := requestActor(r)
+	results := bin.Restore(ctx, actor, keys...)
+	restored := 0
+	for _, result := range results {
+		if result == trash.Restored {
+			restored++
+		}
+	}
+	log.Printf("API: %s restored %d of %d alarms from the recycle bin", actor, restored, len(keys))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+}
+
+func purgeTrash(w http.ResponseWriter, r *http.Request, bin *trash.Bin, req trashRequest) {
+	ctx := context.Background()
+	actor := requestActor(r)
+	var purged int
+	var err error
+	if req.All {
+		purged, err = bin.PurgeBefore(ctx, actor, time.Time{})
+	} else {
+		purged, err = bin.Purge(ctx, actor, req.Keys...)
+	}
+	if err != nil {
+		log.Printf("Failed to purge recycle bin: %v", err)
+		http.Error(w, "Failed to purge recycle bin", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"purged": purged})
+}